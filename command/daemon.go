@@ -1,6 +1,11 @@
 package command
 
 import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
 	"github.com/pact-foundation/pact-go/daemon"
 	"github.com/spf13/cobra"
 )
@@ -8,6 +13,18 @@ import (
 var port int
 var network string
 var address string
+var token string
+var tlsCert string
+var tlsKey string
+var tlsClientCA string
+var stateDir string
+var restartPolicy string
+var maxRestarts int
+var logDir string
+var pactBinDir string
+var mockServicePath string
+var verifierPath string
+var lockFile string
 var daemonCmdInstance *daemon.Daemon
 var daemonCmd = &cobra.Command{
 	Use:   "daemon",
@@ -15,19 +32,79 @@ var daemonCmd = &cobra.Command{
 	Long:  `Creates a daemon for the Pact DSLs to communicate with`,
 	Run: func(cmd *cobra.Command, args []string) {
 		setLogLevel(verbose, logLevel)
-
-		mock := &daemon.MockService{}
-		mock.Setup()
-		verifier := &daemon.VerificationService{}
-		verifier.Setup()
-		daemonCmdInstance = daemon.NewDaemon(mock, verifier)
+		daemonCmdInstance = newDaemon()
 		daemonCmdInstance.StartDaemon(port, network, address)
 	},
 }
 
+// newDaemon returns a Daemon configured by the flags, exiting if the pact
+// tools can't be found or the configuration is invalid.
+func newDaemon() *daemon.Daemon {
+	if stateDir == "" {
+		stateDir = filepath.Join(os.TempDir(), "pact-go", fmt.Sprintf("daemon-%d", port))
+	}
+	switch daemon.RestartPolicy(restartPolicy) {
+	case daemon.RestartNever, daemon.RestartOnFailure, daemon.RestartAlways:
+	default:
+		log.Fatalln("[ERROR] unknown restart policy:", restartPolicy)
+	}
+
+	mockServiceBinary := findTool(daemon.MockServiceTool, mockServicePath)
+	verifierBinary := findTool(daemon.VerifierTool, verifierPath)
+
+	mock := &daemon.MockService{Path: mockServiceBinary}
+	mock.StateDir = stateDir
+	mock.RestartPolicy = daemon.RestartPolicy(restartPolicy)
+	mock.MaxRestarts = maxRestarts
+	mock.LogDir = logDir
+	verifier := &daemon.VerificationService{Path: verifierBinary}
+	d := daemon.NewDaemon(mock, verifier)
+	d.Token = token
+	d.LockFile = lockFile
+
+	if tlsCert != "" || tlsKey != "" {
+		config, err := daemon.NewTLSConfig(tlsCert, tlsKey, tlsClientCA)
+		if err != nil {
+			log.Fatalln("[ERROR] unable to configure TLS:", err)
+		}
+		d.TLSConfig = config
+	}
+
+	return d
+}
+
+// findTool finds the given pact tool, exiting if it cannot be found or is
+// not a supported version.
+func findTool(name string, path string) string {
+	path, err := daemon.FindTool(name, path, pactBinDir)
+	if err != nil {
+		log.Fatalln("[ERROR]", err)
+	}
+
+	version, err := daemon.CheckToolVersion(name, path)
+	if err != nil {
+		log.Fatalln("[ERROR]", err)
+	}
+	log.Printf("[DEBUG] using %s %s (%s)\n", name, version, path)
+
+	return path
+}
+
 func init() {
 	daemonCmd.Flags().IntVarP(&port, "port", "p", 6666, "Local daemon port to listen on")
-	daemonCmd.Flags().StringVarP(&network, "network", "n", "tcp", "Local network interface to listen on ('tcp', 'tcp4', 'tcp6')")
-	daemonCmd.Flags().StringVarP(&address, "address", "a", "", "Local network address to listen on (e.g. '', '127.0.0.1', '[::1]' etc.)")
+	daemonCmd.Flags().StringVarP(&network, "network", "n", "tcp", "Local network interface to listen on ('tcp', 'tcp4', 'tcp6', 'unix')")
+	daemonCmd.Flags().StringVarP(&address, "address", "a", "", "Local network address to listen on (e.g. '', '127.0.0.1', '[::1]' etc.), or the socket path for 'unix'")
+	daemonCmd.Flags().StringVar(&token, "token", os.Getenv("PACT_DAEMON_TOKEN"), "Shared token clients must present to use the daemon (defaults to $PACT_DAEMON_TOKEN)")
+	daemonCmd.Flags().StringVar(&tlsCert, "tls-cert", "", "PEM encoded certificate to serve the daemon over TLS")
+	daemonCmd.Flags().StringVar(&tlsKey, "tls-key", "", "PEM encoded private key of the TLS certificate")
+	daemonCmd.Flags().StringVar(&tlsClientCA, "tls-client-ca", "", "PEM encoded CA certificate(s) clients must present a certificate signed by")
+	daemonCmd.Flags().StringVar(&stateDir, "state-dir", "", "Directory to keep mock server pidfiles in, reaping any orphaned by a previous daemon on start (defaults to a directory per port in the system temp dir)")
+	daemonCmd.Flags().StringVar(&restartPolicy, "restart", string(daemon.RestartNever), "Restart policy for mock servers that crash ('never', 'on-failure', 'always')")
+	daemonCmd.Flags().IntVar(&maxRestarts, "max-restarts", 3, "Maximum number of times a mock server is restarted")
+	daemonCmd.Flags().StringVar(&logDir, "log-dir", "", "Directory to write the output of each mock server to, in a file named after its pid")
+	daemonCmd.Flags().StringVar(&pactBinDir, "pact-bin-dir", os.Getenv("PACT_BIN_DIR"), "Directory containing the pact tools (defaults to $PACT_BIN_DIR, then pact/bin next to pact-go, then the PATH)")
+	daemonCmd.Flags().StringVar(&mockServicePath, "mock-service-path", os.Getenv("PACT_MOCK_SERVICE_PATH"), "Path to the pact-mock-service binary (defaults to $PACT_MOCK_SERVICE_PATH)")
+	daemonCmd.Flags().StringVar(&verifierPath, "verifier-path", os.Getenv("PACT_PROVIDER_VERIFIER_PATH"), "Path to the pact-provider-verifier binary (defaults to $PACT_PROVIDER_VERIFIER_PATH)")
+	daemonCmd.Flags().StringVar(&lockFile, "lock-file", daemon.DefaultLockFile(), "File to record the daemon's address in, for Pacts using an embedded daemon to discover it ('' to disable)")
 	RootCmd.AddCommand(daemonCmd)
 }
@@ -2,10 +2,16 @@ package command
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
+	"github.com/pact-foundation/pact-go/daemon"
+
 	"github.com/pact-foundation/pact-go/utils"
 )
 
@@ -26,13 +32,38 @@ func waitForPortInTest(port int, t *testing.T) {
 	}
 }
 
+// createTools creates fake pact tools, that print their version, in a new
+// directory.
+func createTools(t *testing.T) string {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	tools := map[string]string{daemon.MockServiceTool: "2.6.4", daemon.VerifierTool: "1.11.0"}
+	for name, version := range tools {
+		path := filepath.Join(dir, name)
+		script := fmt.Sprintf("#!/bin/sh\necho %s\n", version)
+		if runtime.GOOS == "windows" {
+			path += ".bat"
+			script = fmt.Sprintf("@echo %s\r\n", version)
+		}
+		if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	return dir
+}
+
 func TestDaemonCommand(t *testing.T) {
-	args := []string{"daemon"}
 	p, _ := utils.GetFreePort()
 	port = p
 	network = "tcp"
-	go daemonCmd.Run(nil, args)
+	pactBinDir = createTools(t)
+	defer os.RemoveAll(pactBinDir)
+	lockFile = filepath.Join(pactBinDir, "daemon.lock")
+
+	// Build the daemon here, rather than reading the one daemonCmd.Run
+	// creates from another goroutine
+	d := newDaemon()
+	go d.StartDaemon(port, network, address)
 
 	waitForPortInTest(port, t)
-	daemonCmdInstance.Shutdown()
+	d.Shutdown()
 }
@@ -15,7 +15,9 @@ package daemon
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -23,9 +25,15 @@ import (
 	"net/http"
 	"net/rpc"
 	"os"
+	"os/exec"
 	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/pact-foundation/pact-go/types"
+	"github.com/pact-foundation/pact-go/utils"
 )
 
 // Daemon wraps the commands for the RPC server.
@@ -33,6 +41,61 @@ type Daemon struct {
 	pactMockSvcManager     Service
 	verificationSvcManager Service
 	signalChan             chan os.Signal
+
+	// verificationMutex guards the creation of verifier commands and the
+	// running verifications, as provider verifications may be requested
+	// concurrently.
+	verificationMutex *sync.Mutex
+	verifications     map[string]*verification
+
+	// startMutex guards the allocation of ports to, and creation of, mock
+	// server commands, as NewService configures the shared Mock Service
+	// manager with the request arguments.
+	startMutex *sync.Mutex
+
+	// servers maps the pid of each started Mock Server to its address.
+	serverMutex *sync.Mutex
+	servers     map[int]serverAddress
+
+	// events records the lifecycle events of the Mock Servers for clients.
+	events *serverEvents
+
+	// Token, if set, must be presented by clients as a bearer token in the
+	// Authorization header.
+	Token string
+
+	// TLSConfig, if set, serves the RPC interfaces over TLS.
+	TLSConfig *tls.Config
+
+	// LockFile, if set, records where the Daemon started by StartDaemon is
+	// listening whilst it is running, so that clients can discover it.
+	LockFile string
+}
+
+// serverAddress is the host and port a Mock Server was started on, and
+// whether it has exited, releasing its port.
+type serverAddress struct {
+	host   string
+	port   int
+	exited bool
+}
+
+// verification is a running provider verification process.
+type verification struct {
+	cmd *exec.Cmd
+
+	// stopped receives the reason the process was stopped early, if any.
+	stopped chan string
+}
+
+// stop kills the verification process, recording the reason.
+func (v *verification) stop(reason string) {
+	select {
+	case v.stopped <- reason:
+		log.Println("[DEBUG] daemon - stopping verification:", reason)
+		v.cmd.Process.Kill()
+	default:
+	}
 }
 
 // NewDaemon returns a new Daemon with all instance variables initialised.
@@ -40,44 +103,134 @@ func NewDaemon(MockServiceManager Service, verificationServiceManager Service) *
 	MockServiceManager.Setup()
 	verificationServiceManager.Setup()
 
-	return &Daemon{
+	events := &serverEvents{}
+	subscription, _ := MockServiceManager.Subscribe()
+	go events.record(subscription)
+
+	d := &Daemon{
 		pactMockSvcManager:     MockServiceManager,
 		verificationSvcManager: verificationServiceManager,
 		signalChan:             make(chan os.Signal, 1),
+		verificationMutex:      &sync.Mutex{},
+		verifications:          make(map[string]*verification),
+		startMutex:             &sync.Mutex{},
+		serverMutex:            &sync.Mutex{},
+		servers:                make(map[int]serverAddress),
+		events:                 events,
 	}
+	exits, _ := MockServiceManager.Subscribe()
+	go d.releasePorts(exits)
+
+	return d
 }
 
-// StartDaemon starts the daemon RPC server.
+// releasePorts releases the port reserved by a Mock Server when it exits on
+// its own, and reserves it again if the Mock Server is restarted, until the
+// channel is closed. The port of a stopped Mock Server is released by
+// StopServer.
+func (d Daemon) releasePorts(events <-chan ServiceEvent) {
+	for event := range events {
+		if event.Type != ServiceExited && (event.Type != ServiceStarted || event.Restarts == 0) {
+			continue
+		}
+
+		// The event may be received before StartServer records the address
+		d.serverMutex.Lock()
+		address := d.servers[event.Pid]
+		address.exited = event.Type == ServiceExited
+		d.servers[event.Pid] = address
+		d.serverMutex.Unlock()
+	}
+}
+
+// StartDaemon starts the daemon RPC server, serving until it receives a
+// signal or is stopped with StopDaemon.
 func (d Daemon) StartDaemon(port int, network string, address string) {
 	log.Println("[INFO] daemon - starting daemon on network:", network, "address:", address, "port:", port)
 
-	serv := rpc.NewServer()
-	serv.Register(d)
+	l, err := d.Listen(port, network, address)
+	if err != nil {
+		panic(err)
+	}
 
-	// Workaround for multiple RPC ServeMux's
-	oldMux := http.DefaultServeMux
-	mux := http.NewServeMux()
-	http.DefaultServeMux = mux
+	if d.LockFile != "" {
+		if err = writeLockFile(d.LockFile, network, address, port); err != nil {
+			log.Println("[WARN] daemon - unable to write lock file:", err)
+		}
+		defer removeLockFile(d.LockFile)
+	}
 
-	serv.HandleHTTP(rpc.DefaultRPCPath, rpc.DefaultDebugPath)
+	// Wait for sigterm
+	signal.Notify(d.signalChan, os.Interrupt, os.Kill)
+	d.Serve(l)
+}
 
-	// Workaround for multiple RPC ServeMux's
-	http.DefaultServeMux = oldMux
+// Listen listens on the given network and address (and port, if not a unix
+// network), serving over TLS if the Daemon has a TLSConfig.
+func (d Daemon) Listen(port int, network string, address string) (net.Listener, error) {
+	listenAddress := fmt.Sprintf("%s:%d", address, port)
+	if isUnixNetwork(network) {
+		removeStaleSocket(network, address)
+		listenAddress = address
+	}
 
-	l, err := net.Listen(network, fmt.Sprintf("%s:%d", address, port))
+	l, err := net.Listen(network, listenAddress)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if d.TLSConfig != nil {
+		log.Println("[INFO] daemon - serving over TLS")
+		l = tls.NewListener(l, d.TLSConfig)
 	}
-	go http.Serve(l, mux)
 
-	// Wait for sigterm
-	signal.Notify(d.signalChan, os.Interrupt, os.Kill)
+	return l, nil
+}
+
+// Serve serves the RPC interfaces on the listener until the Daemon is stopped
+// with StopDaemon, then closes the listener and shuts down all services.
+func (d Daemon) Serve(l net.Listener) {
+	serv := rpc.NewServer()
+	serv.Register(d)
+
+	// The RPC server is served on its own mux, rather than the default mux
+	// HandleHTTP uses, as the Daemon may be embedded in another process
+	mux := http.NewServeMux()
+	mux.Handle(rpc.DefaultRPCPath, serv)
+	mux.Handle(DefaultJSONRPCPath, jsonRPCHandler(serv))
+
+	go http.Serve(l, authenticate(d.Token, mux))
+
 	s := <-d.signalChan
 	log.Println("[INFO] daemon - received signal:", s, ", shutting down all services")
 
+	// Stop accepting requests, removing the socket file of a unix listener
+	l.Close()
 	d.Shutdown()
 }
 
+// isUnixNetwork returns true for the unix domain socket networks.
+func isUnixNetwork(network string) bool {
+	return strings.HasPrefix(network, "unix")
+}
+
+// removeStaleSocket removes a socket file left behind by a Daemon that did
+// not shut down cleanly, so that it can be listened on again. A socket that
+// is still being listened on is left alone.
+func removeStaleSocket(network string, path string) {
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+
+	conn, err := net.Dial(network, path)
+	if err == nil {
+		conn.Close()
+		return
+	}
+
+	log.Println("[DEBUG] daemon - removing stale socket:", path)
+	os.Remove(path)
+}
+
 // StopDaemon allows clients to programmatically shuts down the running Daemon
 // via RPC.
 func (d Daemon) StopDaemon(request string, reply *string) error {
@@ -89,26 +242,224 @@ func (d Daemon) StopDaemon(request string, reply *string) error {
 // Shutdown ensures all services are cleanly destroyed.
 func (d Daemon) Shutdown() {
 	log.Println("[DEBUG] daemon - shutdown")
-	for _, s := range d.verificationSvcManager.List() {
-		if s != nil {
-			d.pactMockSvcManager.Stop(s.Process.Pid)
-		}
+	for pid := range d.pactMockSvcManager.List() {
+		d.pactMockSvcManager.Stop(pid)
+	}
+
+	d.verificationMutex.Lock()
+	defer d.verificationMutex.Unlock()
+	for _, v := range d.verifications {
+		v.stop("the Daemon is shutting down")
 	}
 }
 
 // StartServer starts a mock server and returns a pointer to atypes.MockServer
-// struct.
+// struct. If no Port is requested, the Daemon allocates one that is free and
+// not reserved by another of its Mock Servers.
 func (d Daemon) StartServer(request types.MockServer, reply *types.MockServer) error {
 	log.Println("[DEBUG] daemon - starting mock server with args:", request.Args)
 	server := &types.MockServer{}
-	svc := d.pactMockSvcManager.NewService(request.Args)
+	args := request.Args
+
+	// Allocating ports and starting Mock Servers together reserves each port
+	d.startMutex.Lock()
+	defer d.startMutex.Unlock()
+
+	port := request.Port
+	if port != 0 {
+		if !utils.PortAvailable(request.Host, port) || d.portReserved(port) {
+			log.Println("[ERROR] daemon - mock server port in use:", port)
+			return fmt.Errorf("unable to start mock server: port %d is already in use", request.Port)
+		}
+	} else {
+		var err error
+		port, err = d.allocatePort(request.AllowedPorts, request.Host)
+		if err != nil {
+			log.Println("[ERROR] daemon - unable to allocate mock server port:", err)
+			return fmt.Errorf("unable to start mock server: %s", err)
+		}
+		args = append(args, "--port", strconv.Itoa(port))
+	}
+
+	host := request.Host
+	if host == "" {
+		host = "localhost"
+	} else if !hasArg(args, "--host") {
+		args = append(args, "--host", strings.Trim(host, "[]"))
+	}
+
+	svc := d.pactMockSvcManager.NewService(args)
 	server.Status = -1
-	cmd := svc.Start()
+	cmd, err := svc.Start()
+	if err != nil {
+		return fmt.Errorf("unable to start mock server: %s", err)
+	}
 	server.Pid = cmd.Process.Pid
+	server.Port = port
+	server.State = types.MockServerStarting
+
+	d.serverMutex.Lock()
+	address := d.servers[server.Pid]
+	address.host, address.port = host, server.Port
+	d.servers[server.Pid] = address
+	d.serverMutex.Unlock()
+
 	*reply = *server
 	return nil
 }
 
+// hasArg reports whether the flag is given in the arguments.
+func hasArg(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag || strings.HasPrefix(arg, flag+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// allocatePort finds a port that is free on the host and not reserved by a
+// Mock Server, from the allowed ports if given.
+func (d Daemon) allocatePort(allowed string, host string) (int, error) {
+	if allowed == "" {
+		for i := 0; i < 10; i++ {
+			port, err := utils.GetFreePortOnHost(host)
+			if err != nil {
+				return 0, err
+			}
+			if !d.portReserved(port) {
+				return port, nil
+			}
+		}
+		return 0, errors.New("no free port found")
+	}
+
+	ports, err := utils.ParsePorts(allowed)
+	if err != nil {
+		return 0, fmt.Errorf("invalid allowed ports %s: %s", allowed, err)
+	}
+	for _, port := range ports {
+		if !d.portReserved(port) && utils.PortAvailable(host, port) {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free port found in %s", allowed)
+}
+
+// portReserved reports whether the port is used by a Mock Server that has
+// been started, and has not yet been stopped or exited.
+func (d Daemon) portReserved(port int) bool {
+	d.serverMutex.Lock()
+	defer d.serverMutex.Unlock()
+
+	for _, address := range d.servers {
+		if address.port == port && !address.exited {
+			return true
+		}
+	}
+	return false
+}
+
+// ServerStatus returns the current state of the Mock Server with the given
+// Pid (or Port, if no Pid is given): whether it is starting, ready to accept
+// connections or has exited, and its exit code.
+func (d Daemon) ServerStatus(request types.MockServer, reply *types.MockServer) error {
+	log.Println("[DEBUG] daemon - mock server status")
+	pid, err := d.findServer(request)
+	if err != nil {
+		return err
+	}
+	*reply = d.serverStatus(pid)
+
+	return nil
+}
+
+// ServerLogs returns the current state of the Mock Server with the given Pid
+// (or Port, if no Pid is given), along with its most recent output.
+func (d Daemon) ServerLogs(request types.MockServer, reply *types.MockServer) error {
+	log.Println("[DEBUG] daemon - mock server logs")
+	pid, err := d.findServer(request)
+	if err != nil {
+		return err
+	}
+
+	server := d.serverStatus(pid)
+	server.Logs, err = d.pactMockSvcManager.Logs(pid)
+	if err != nil {
+		log.Println("[DEBUG] daemon - no mock server logs:", err)
+	}
+	*reply = server
+
+	return nil
+}
+
+// ServerEvents returns the lifecycle events of the Mock Servers since the
+// event with the Seq given, in order: when each is started or restarted, and
+// when it is stopped or exits. Clients, such as test packages running in
+// parallel against one Daemon, poll it to follow their Mock Servers.
+func (d Daemon) ServerEvents(request types.MockServerEventsRequest, reply *[]types.MockServerEvent) error {
+	log.Println("[DEBUG] daemon - mock server events since:", request.Since)
+	*reply = d.events.since(request.Since, request.Pid)
+
+	return nil
+}
+
+// findServer returns the pid of the started Mock Server with the Pid, or
+// Port if no Pid is given, of the request.
+func (d Daemon) findServer(request types.MockServer) (int, error) {
+	pid := request.Pid
+
+	d.serverMutex.Lock()
+	_, ok := d.servers[pid]
+	if !ok && pid == 0 && request.Port != 0 {
+		for p, address := range d.servers {
+			if address.port == request.Port {
+				pid, ok = p, true
+			}
+		}
+	}
+	d.serverMutex.Unlock()
+
+	if !ok {
+		return 0, fmt.Errorf("no mock server with pid %d or port %d", request.Pid, request.Port)
+	}
+
+	return pid, nil
+}
+
+// serverStatus determines the lifecycle state of the given Mock Server.
+func (d Daemon) serverStatus(pid int) types.MockServer {
+	d.serverMutex.Lock()
+	address := d.servers[pid]
+	d.serverMutex.Unlock()
+
+	server := types.MockServer{
+		Pid:    pid,
+		Port:   address.port,
+		Host:   address.host,
+		Status: -1,
+		State:  types.MockServerStarting,
+	}
+
+	if exited, code := d.pactMockSvcManager.Exited(pid); exited {
+		server.State = types.MockServerExited
+		server.Status = code
+		server.Error = fmt.Sprintf("mock server exited with code %d", code)
+		return server
+	}
+
+	if address.port != 0 {
+		hostPort := net.JoinHostPort(strings.Trim(address.host, "[]"), strconv.Itoa(address.port))
+		conn, err := net.DialTimeout("tcp", hostPort, 100*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			server.State = types.MockServerReady
+		}
+	}
+
+	return server
+}
+
 // VerifyProvider runs the Pact Provider Verification Process.
 func (d Daemon) VerifyProvider(request types.VerifyRequest, reply *types.ProviderVerifierResponse) error {
 	log.Println("[DEBUG] daemon - verifying provider")
@@ -128,8 +479,17 @@ func (d Daemon) VerifyProvider(request types.VerifyRequest, reply *types.Provide
 	// First, attempt to decode the response of the stdout.
 	// If that is successful, we are at case 3. Return stdout as message, no error.
 	// Else, return an error, include stderr and stdout in both the error and message.
+	d.verificationMutex.Lock()
 	svc := d.verificationSvcManager.NewService(request.Args)
 	cmd := svc.Command()
+	d.verificationMutex.Unlock()
+	if len(request.Env) > 0 {
+		env := cmd.Env
+		if env == nil {
+			env = os.Environ()
+		}
+		cmd.Env = append(env, request.Env...)
+	}
 
 	stdOutPipe, err := cmd.StdoutPipe()
 	if err != nil {
@@ -143,6 +503,26 @@ func (d Daemon) VerifyProvider(request types.VerifyRequest, reply *types.Provide
 	if err != nil {
 		return err
 	}
+
+	// Allow the verification to be stopped by a timeout or cancellation
+	v := &verification{cmd: cmd, stopped: make(chan string, 1)}
+	if request.VerificationID != "" {
+		d.verificationMutex.Lock()
+		d.verifications[request.VerificationID] = v
+		d.verificationMutex.Unlock()
+		defer func() {
+			d.verificationMutex.Lock()
+			delete(d.verifications, request.VerificationID)
+			d.verificationMutex.Unlock()
+		}()
+	}
+	if request.Timeout > 0 {
+		timer := time.AfterFunc(request.Timeout, func() {
+			v.stop(fmt.Sprintf("timed out after %s", request.Timeout))
+		})
+		defer timer.Stop()
+	}
+
 	stdOut, err := ioutil.ReadAll(stdOutPipe)
 	if err != nil {
 		return err
@@ -154,6 +534,12 @@ func (d Daemon) VerifyProvider(request types.VerifyRequest, reply *types.Provide
 
 	err = cmd.Wait()
 
+	select {
+	case reason := <-v.stopped:
+		return fmt.Errorf("error verifying provider: verification %s", reason)
+	default:
+	}
+
 	decoder := json.NewDecoder(bytes.NewReader(stdOut))
 	dErr := decoder.Decode(&reply)
 	if dErr == nil {
@@ -167,16 +553,30 @@ func (d Daemon) VerifyProvider(request types.VerifyRequest, reply *types.Provide
 	return fmt.Errorf("error verifying provider: %s\n\nSTDERR:\n%s\n\nSTDOUT:\n%s", err, stdErr, stdOut)
 }
 
+// CancelVerification stops the running provider verification with the given
+// VerificationID.
+func (d Daemon) CancelVerification(request string, reply *string) error {
+	log.Println("[DEBUG] daemon - cancel verification", request)
+	d.verificationMutex.Lock()
+	v, ok := d.verifications[request]
+	d.verificationMutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no running verification with id: %s", request)
+	}
+	v.stop("cancelled")
+
+	return nil
+}
+
 // ListServers returns a slice of all running types.MockServers.
 func (d Daemon) ListServers(request types.MockServer, reply *types.PactListResponse) error {
 	log.Println("[DEBUG] daemon - listing mock servers")
 	var servers []*types.MockServer
 
-	for port, s := range d.pactMockSvcManager.List() {
-		servers = append(servers, &types.MockServer{
-			Pid:  s.Process.Pid,
-			Port: port,
-		})
+	for pid := range d.pactMockSvcManager.List() {
+		server := d.serverStatus(pid)
+		servers = append(servers, &server)
 	}
 
 	*reply = types.PactListResponse{
@@ -190,10 +590,18 @@ func (d Daemon) ListServers(request types.MockServer, reply *types.PactListRespo
 func (d Daemon) StopServer(request types.MockServer, reply *types.MockServer) error {
 	log.Println("[DEBUG] daemon - stopping mock server")
 	success, err := d.pactMockSvcManager.Stop(request.Pid)
+
+	d.serverMutex.Lock()
+	delete(d.servers, request.Pid)
+	d.serverMutex.Unlock()
+
 	if success == true && err == nil {
 		request.Status = 0
 	} else {
 		request.Status = 1
+		if err != nil {
+			request.Error = err.Error()
+		}
 	}
 	*reply = request
 
@@ -3,11 +3,15 @@ package daemon
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net"
 	"net/rpc"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -82,6 +86,15 @@ func TestShutdownDaemon(t *testing.T) {
 	d.Shutdown()
 }
 
+func TestShutdownDaemon_StopsMockServers(t *testing.T) {
+	d, manager := createMockedDaemon(true)
+	d.Shutdown()
+
+	if manager.ServiceStopCount != 3 {
+		t.Fatalf("Expected 3 mock servers to be stopped but got: %d", manager.ServiceStopCount)
+	}
+}
+
 // Use this to wait for a daemon to be running prior
 // to running tests
 func connectToDaemon(port int, t *testing.T) {
@@ -111,11 +124,11 @@ func waitForDaemonToShutdown(port int, daemon *Daemon, t *testing.T) {
 			t.Fatalf("Expected server to shutdown < 1s.")
 		case <-time.After(50 * time.Millisecond):
 			conn, err := net.Dial("tcp", fmt.Sprintf(":%d", port))
-			conn.SetReadDeadline(time.Now())
-			defer conn.Close()
 			if err != nil {
 				return
 			}
+			conn.SetReadDeadline(time.Now())
+			defer conn.Close()
 			buffer := make([]byte, 8)
 			_, err = conn.Read(buffer)
 			if err != nil {
@@ -133,6 +146,53 @@ func TestStartAndStopDaemon(t *testing.T) {
 	connectToDaemon(port, t)
 }
 
+func TestStartDaemon_UnixSocket(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "daemon.sock")
+
+	// A socket left behind by a previous daemon should be replaced
+	ioutil.WriteFile(socket, []byte{}, 0600)
+
+	daemon, _ := createMockedDaemon(true)
+	go daemon.StartDaemon(0, "unix", socket)
+
+	var client *rpc.Client
+	var err error
+	timeout := time.After(1 * time.Second)
+	for client == nil {
+		select {
+		case <-timeout:
+			t.Fatalf("Expected daemon to listen on %s: %v", socket, err)
+		case <-time.After(50 * time.Millisecond):
+			client, err = rpc.DialHTTP("unix", socket)
+		}
+	}
+	defer client.Close()
+
+	var res types.PactListResponse
+	err = client.Call("Daemon.ListServers", types.MockServer{}, &res)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(res.Servers) != 3 {
+		t.Fatalf("Expected 3 servers to be listed, got: %d", len(res.Servers))
+	}
+
+	daemon.signalChan <- os.Interrupt
+	timeout = time.After(1 * time.Second)
+	for {
+		select {
+		case <-timeout:
+			t.Fatalf("Expected socket to be removed on shutdown")
+		case <-time.After(50 * time.Millisecond):
+			if _, err = os.Stat(socket); os.IsNotExist(err) {
+				return
+			}
+		}
+	}
+}
+
 func TestDaemonShutdown(t *testing.T) {
 	daemon, manager := createMockedDaemon(true)
 	daemon.Shutdown()
@@ -156,11 +216,305 @@ func TestStartServer(t *testing.T) {
 		t.Fatalf("Expected non-zero Pid but got: %d", res.Pid)
 	}
 
-	if res.Port != 0 {
+	if res.Port == 0 {
 		t.Fatalf("Expected non-zero port but got: %d", res.Port)
 	}
 }
 
+func TestStartServer_AllowedPorts(t *testing.T) {
+	daemon, _ := createMockedDaemon(true)
+	first, _ := utils.GetFreePort()
+	second, _ := utils.GetFreePort()
+	allowed := fmt.Sprintf("%d,%d", first, second)
+
+	// Ports are reserved until the mock server is stopped
+	var res types.MockServer
+	for _, port := range []int{first, second} {
+		err := daemon.StartServer(types.MockServer{AllowedPorts: allowed}, &res)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if res.Port != port {
+			t.Fatalf("Expected port %d but got: %d", port, res.Port)
+		}
+	}
+
+	err := daemon.StartServer(types.MockServer{AllowedPorts: allowed}, &res)
+	if err == nil || !strings.Contains(err.Error(), "no free port found") {
+		t.Fatalf("Expected no free port error but got: %v", err)
+	}
+
+	err = daemon.StartServer(types.MockServer{Port: first}, &res)
+	if err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Fatalf("Expected port in use error but got: %v", err)
+	}
+
+	var stopped types.MockServer
+	daemon.StopServer(types.MockServer{Pid: res.Pid}, &stopped)
+	err = daemon.StartServer(types.MockServer{AllowedPorts: allowed}, &res)
+	if err != nil || res.Port != second {
+		t.Fatalf("Expected released port %d to be allocated but got: %d (%v)", second, res.Port, err)
+	}
+}
+
+func TestStartServer_PortInUseOnHost(t *testing.T) {
+	daemon, _ := createMockedDaemon(true)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	var res types.MockServer
+	err = daemon.StartServer(types.MockServer{Port: port, Host: "127.0.0.1"}, &res)
+	if err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Fatalf("Expected port in use error but got: %v", err)
+	}
+
+	// The port is free on another host
+	other, err := net.Listen("tcp", fmt.Sprintf("127.0.0.2:%d", port))
+	if err != nil {
+		t.Skip("no 127.0.0.2 loopback address:", err)
+	}
+	other.Close()
+	if err = daemon.StartServer(types.MockServer{Port: port, Host: "127.0.0.2"}, &res); err != nil {
+		t.Fatalf("Expected port %d to be available on 127.0.0.2 but got: %v", port, err)
+	}
+}
+
+func TestStartServer_ExitedReleasesPort(t *testing.T) {
+	daemon, manager := createMockedDaemon(true)
+	port, _ := utils.GetFreePort()
+	allowed := strconv.Itoa(port)
+
+	var res types.MockServer
+	if err := daemon.StartServer(types.MockServer{AllowedPorts: allowed}, &res); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// The port is released when the Mock Server exits, and reserved again if
+	// it is restarted
+	manager.publish(ServiceEvent{Type: ServiceExited, Pid: res.Pid})
+	waitForPortReserved(daemon, port, false, t)
+	manager.publish(ServiceEvent{Type: ServiceStarted, Pid: res.Pid, Restarts: 1})
+	waitForPortReserved(daemon, port, true, t)
+	manager.publish(ServiceEvent{Type: ServiceExited, Pid: res.Pid, Restarts: 1})
+	waitForPortReserved(daemon, port, false, t)
+
+	var next types.MockServer
+	if err := daemon.StartServer(types.MockServer{AllowedPorts: allowed}, &next); err != nil || next.Port != port {
+		t.Fatalf("Expected released port %d to be allocated but got: %d (%v)", port, next.Port, err)
+	}
+}
+
+// waitForPortReserved waits for the events published to be received, until
+// the port is reserved or released as expected.
+func waitForPortReserved(daemon *Daemon, port int, reserved bool, t *testing.T) {
+	timeout := time.After(1 * time.Second)
+	for daemon.portReserved(port) != reserved {
+		select {
+		case <-timeout:
+			t.Fatalf("Expected port %d reserved to be %v", port, reserved)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestStartServer_InvalidAllowedPorts(t *testing.T) {
+	daemon, _ := createMockedDaemon(true)
+
+	var res types.MockServer
+	err := daemon.StartServer(types.MockServer{AllowedPorts: "abc"}, &res)
+	if err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+}
+
+func TestStartServer_Fail(t *testing.T) {
+	daemon, manager := createMockedDaemon(true)
+	manager.ServiceStartError = errors.New("unable to find service binary")
+
+	res := types.MockServer{}
+	err := daemon.StartServer(types.MockServer{}, &res)
+	if err == nil || !strings.Contains(err.Error(), "unable to find service binary") {
+		t.Fatalf("Expected start error but got: %v", err)
+	}
+}
+
+func TestStartServer_PortInUse(t *testing.T) {
+	daemon, manager := createMockedDaemon(true)
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer l.Close()
+
+	req := types.MockServer{Port: l.Addr().(*net.TCPAddr).Port}
+	res := types.MockServer{}
+	err = daemon.StartServer(req, &res)
+	if err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Fatalf("Expected port in use error but got: %v", err)
+	}
+
+	if manager.ServiceStartCount != 0 {
+		t.Fatalf("Expected no server to be started but got: %d", manager.ServiceStartCount)
+	}
+}
+
+func TestServerStatus(t *testing.T) {
+	daemon, manager := createMockedDaemon(true)
+
+	server := types.MockServer{}
+	err := daemon.StartServer(types.MockServer{}, &server)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	var res types.MockServer
+	err = daemon.ServerStatus(types.MockServer{Pid: server.Pid}, &res)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if res.State != types.MockServerStarting || res.Status != -1 {
+		t.Fatalf("Expected server to be starting but got: %s (%d)", res.State, res.Status)
+	}
+
+	manager.ServiceExitCodes = map[int]int{server.Pid: 2}
+	err = daemon.ServerStatus(types.MockServer{Pid: server.Pid}, &res)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if res.State != types.MockServerExited || res.Status != 2 {
+		t.Fatalf("Expected server to have exited with code 2 but got: %s (%d)", res.State, res.Status)
+	}
+}
+
+func TestServerStatus_Ready(t *testing.T) {
+	daemon, _ := createMockedDaemon(true)
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+	daemon.servers[1234] = serverAddress{host: "localhost", port: port}
+
+	var res types.MockServer
+	err = daemon.ServerStatus(types.MockServer{Port: port}, &res)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if res.State != types.MockServerReady || res.Pid != 1234 {
+		t.Fatalf("Expected server 1234 to be ready but got: %s (%d)", res.State, res.Pid)
+	}
+}
+
+func TestServerStatus_ReadyOnHost(t *testing.T) {
+	daemon, manager := createMockedDaemon(true)
+	l, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skip("no 127.0.0.2 loopback address:", err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	var server types.MockServer
+	err = daemon.StartServer(types.MockServer{Host: "127.0.0.2"}, &server)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !hasArg(manager.Args, "--host") {
+		t.Fatalf("Expected the mock server to be started on the host but got args: %v", manager.Args)
+	}
+	daemon.servers[server.Pid] = serverAddress{host: "127.0.0.2", port: port}
+
+	var res types.MockServer
+	err = daemon.ServerStatus(types.MockServer{Pid: server.Pid}, &res)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if res.State != types.MockServerReady || res.Host != "127.0.0.2" {
+		t.Fatalf("Expected server to be ready on 127.0.0.2 but got: %s (%s)", res.State, res.Host)
+	}
+}
+
+func TestServerStatus_Unknown(t *testing.T) {
+	daemon, _ := createMockedDaemon(true)
+
+	var res types.MockServer
+	err := daemon.ServerStatus(types.MockServer{Pid: 1234}, &res)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+}
+
+func TestServerLogs(t *testing.T) {
+	daemon, manager := createMockedDaemon(true)
+
+	server := types.MockServer{}
+	err := daemon.StartServer(types.MockServer{}, &server)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	manager.ServiceLogs = map[int][]string{server.Pid: {"listening", "oh noes!"}}
+
+	var res types.MockServer
+	err = daemon.ServerLogs(types.MockServer{Pid: server.Pid}, &res)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !reflect.DeepEqual(res.Logs, []string{"listening", "oh noes!"}) {
+		t.Fatalf("Expected mock server logs but got: %v", res.Logs)
+	}
+	if res.State != types.MockServerStarting {
+		t.Fatalf("Expected server to be starting but got: %s", res.State)
+	}
+}
+
+func TestServerLogs_Unknown(t *testing.T) {
+	daemon, _ := createMockedDaemon(true)
+
+	var res types.MockServer
+	err := daemon.ServerLogs(types.MockServer{Pid: 1234}, &res)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+}
+
+func TestServerEvents(t *testing.T) {
+	daemon, _ := createMockedDaemon(true)
+
+	var first, second types.MockServer
+	daemon.StartServer(types.MockServer{}, &first)
+	daemon.StartServer(types.MockServer{}, &second)
+	daemon.StopServer(types.MockServer{Pid: first.Pid}, &first)
+
+	expected := []types.MockServerEvent{
+		{Seq: 1, Type: "started", Pid: first.Pid},
+		{Seq: 2, Type: "started", Pid: second.Pid},
+		{Seq: 3, Type: "stopped", Pid: first.Pid},
+	}
+	var events []types.MockServerEvent
+	timeout := time.After(3 * time.Second)
+	for len(events) < len(expected) {
+		select {
+		case <-timeout:
+			t.Fatalf("Expected events %v but got: %v", expected, events)
+		case <-time.After(10 * time.Millisecond):
+			daemon.ServerEvents(types.MockServerEventsRequest{}, &events)
+		}
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("Expected events %v but got: %v", expected, events)
+	}
+
+	daemon.ServerEvents(types.MockServerEventsRequest{Since: 1, Pid: first.Pid}, &events)
+	if !reflect.DeepEqual(events, expected[2:]) {
+		t.Fatalf("Expected events of the server since the first but got: %v", events)
+	}
+}
+
 func TestListServers(t *testing.T) {
 	daemon, _ := createMockedDaemon(true)
 	var res types.PactListResponse
@@ -219,6 +573,10 @@ func TestStopServer_Fail(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error: %v", err)
 	}
+
+	if res.Error != "failed to stop server" {
+		t.Fatalf("Expected failure reason but got: '%s'", res.Error)
+	}
 }
 
 func TestStopServer_FailedStatus(t *testing.T) {
@@ -328,6 +686,100 @@ func TestVerifyProvider_ValidProviderStates(t *testing.T) {
 	}
 }
 
+func TestVerifyProvider_ValidFilters(t *testing.T) {
+	daemon, _ := createMockedDaemon(true)
+
+	req := types.VerifyRequest{
+		ProviderBaseURL:   "http://foo.com",
+		PactURLs:          []string{"foo.json", "bar.json"},
+		FilterDescription: "A request to login",
+		FilterState:       "User .* exists",
+	}
+	res := types.ProviderVerifierResponse{}
+	err := daemon.VerifyProvider(req, &res)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+}
+
+func TestVerifyProvider_Timeout(t *testing.T) {
+	daemon, _ := createMockedDaemon(true)
+
+	req := types.VerifyRequest{
+		ProviderBaseURL: "http://foo.com",
+		PactURLs:        []string{"foo.json", "bar.json"},
+		Timeout:         10 * time.Millisecond,
+	}
+	res := types.ProviderVerifierResponse{}
+	err := daemon.VerifyProvider(req, &res)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	if !strings.Contains(err.Error(), "timed out after 10ms") {
+		t.Fatalf("Expected error message but got '%s'", err.Error())
+	}
+}
+
+func TestVerifyProvider_Cancel(t *testing.T) {
+	daemon, _ := createMockedDaemon(true)
+
+	req := types.VerifyRequest{
+		ProviderBaseURL: "http://foo.com",
+		PactURLs:        []string{"foo.json", "bar.json"},
+		VerificationID:  "1234",
+	}
+	done := make(chan error, 1)
+	go func() {
+		res := types.ProviderVerifierResponse{}
+		done <- daemon.VerifyProvider(req, &res)
+	}()
+
+	var reply string
+	timeout := time.After(1 * time.Second)
+	for daemon.CancelVerification("1234", &reply) != nil {
+		select {
+		case <-timeout:
+			t.Fatal("Expected verification to be running")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	err := <-done
+	if err == nil || !strings.Contains(err.Error(), "verification cancelled") {
+		t.Fatalf("Expected cancellation error but got '%v'", err)
+	}
+}
+
+func TestCancelVerification_NotRunning(t *testing.T) {
+	daemon, _ := createMockedDaemon(true)
+
+	var reply string
+	err := daemon.CancelVerification("1234", &reply)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+}
+
+func TestVerifyProvider_InvalidFilter(t *testing.T) {
+	daemon, _ := createMockedDaemon(true)
+
+	req := types.VerifyRequest{
+		ProviderBaseURL:   "http://foo.com",
+		PactURLs:          []string{"foo.json", "bar.json"},
+		FilterDescription: "(",
+	}
+	res := types.ProviderVerifierResponse{}
+	err := daemon.VerifyProvider(req, &res)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	if !strings.Contains(err.Error(), "Invalid description filter") {
+		t.Fatalf("Expected error message but got '%s'", err.Error())
+	}
+}
+
 func TestRPCClient_List(t *testing.T) {
 	daemon, _ := createMockedDaemon(true)
 	port, _ := utils.GetFreePort()
@@ -365,7 +817,7 @@ func TestRPCClient_StartServer(t *testing.T) {
 		t.Fatalf("Expected non-zero Pid but got: %d", res.Pid)
 	}
 
-	if res.Port != 0 {
+	if res.Port == 0 {
 		t.Fatalf("Expected non-zero port but got: %d", res.Port)
 	}
 }
@@ -464,6 +916,9 @@ func TestHelperProcess(t *testing.T) {
 		return
 	}
 	<-time.After(250 * time.Millisecond)
+	if sleep, err := time.ParseDuration(os.Getenv("GO_HELPER_PROCESS_SLEEP")); err == nil {
+		<-time.After(sleep)
+	}
 
 	// some code here to check arguments perhaps?
 	// Fail :(
@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// embeddedStateDir is the parent of the state directories of the embedded
+// Daemons, one per process.
+func embeddedStateDir() string {
+	return filepath.Join(os.TempDir(), "pact-go", "embedded")
+}
+
+// NewEmbeddedDaemon creates a Daemon to run within another process, such as a
+// test binary using the DSL, with Listen and Serve. The pact tools are found
+// as per FindTool, from the PACT_BIN_DIR, PACT_MOCK_SERVICE_PATH and
+// PACT_PROVIDER_VERIFIER_PATH environment variables. Mock servers left behind
+// by the embedded Daemons of processes that have died are reaped.
+func NewEmbeddedDaemon() *Daemon {
+	root := embeddedStateDir()
+	reapEmbeddedStateDirs(root)
+
+	mock := &MockService{Path: embeddedToolPath(MockServiceTool, "PACT_MOCK_SERVICE_PATH")}
+	mock.StateDir = filepath.Join(root, strconv.Itoa(os.Getpid()))
+	verifier := &VerificationService{Path: embeddedToolPath(VerifierTool, "PACT_PROVIDER_VERIFIER_PATH")}
+
+	return NewDaemon(mock, verifier)
+}
+
+// embeddedToolPath finds the named pact tool for an embedded Daemon. If it
+// cannot be found, the default path is used, so that starting it fails.
+func embeddedToolPath(name string, env string) string {
+	path, err := FindTool(name, os.Getenv(env), os.Getenv("PACT_BIN_DIR"))
+	if err != nil {
+		log.Println("[WARN] daemon -", err)
+		return ""
+	}
+	return path
+}
+
+// reapEmbeddedStateDirs reaps the mock servers recorded in the state
+// directories of embedded Daemons whose process is no longer running.
+func reapEmbeddedStateDirs(root string) {
+	dirs, err := ioutil.ReadDir(root)
+	if err != nil {
+		return
+	}
+
+	for _, dir := range dirs {
+		pid, err := strconv.Atoi(dir.Name())
+		if err != nil || pid == os.Getpid() || processRunning(pid) {
+			continue
+		}
+
+		log.Println("[DEBUG] daemon - reaping embedded daemon state of pid", pid)
+		orphaned := &ServiceManager{StateDir: filepath.Join(root, dir.Name())}
+		orphaned.reapOrphans()
+		os.RemoveAll(orphaned.StateDir)
+	}
+}
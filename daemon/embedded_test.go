@@ -0,0 +1,45 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestNewEmbeddedDaemon(t *testing.T) {
+	d := NewEmbeddedDaemon()
+
+	mock, ok := d.pactMockSvcManager.(*MockService)
+	if !ok {
+		t.Fatalf("Expected a MockService but got: %v", d.pactMockSvcManager)
+	}
+	if mock.StateDir != filepath.Join(embeddedStateDir(), strconv.Itoa(os.Getpid())) {
+		t.Fatalf("Expected a state directory for this process but got: %s", mock.StateDir)
+	}
+}
+
+func TestReapEmbeddedStateDirs(t *testing.T) {
+	root, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(root)
+
+	// A process that has exited, and this one
+	cmd := fakeExecCommand("", true, "")
+	cmd.Run()
+	dead := filepath.Join(root, strconv.Itoa(cmd.Process.Pid))
+	alive := filepath.Join(root, strconv.Itoa(os.Getpid()))
+	os.MkdirAll(dead, 0700)
+	os.MkdirAll(alive, 0700)
+	pidfile := filepath.Join(dead, strconv.Itoa(cmd.Process.Pid)+".pid")
+	ioutil.WriteFile(pidfile, []byte(strconv.Itoa(cmd.Process.Pid)+"\npact-mock-service\n1\n"), 0600)
+
+	reapEmbeddedStateDirs(root)
+
+	if _, err := os.Stat(dead); !os.IsNotExist(err) {
+		t.Fatalf("Expected state of dead process to be removed but got: %v", err)
+	}
+	if _, err := os.Stat(alive); err != nil {
+		t.Fatalf("Expected state of running process to be kept but got: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+)
+
+// DefaultJSONRPCPath is the HTTP path of the JSON-RPC interface to the Daemon,
+// for clients not written in Go. It exposes the same methods as the Go RPC
+// interface, e.g.:
+//
+//	curl -X POST http://localhost:6666/jsonrpc \
+//	  -d '{"method": "Daemon.ListServers", "params": [{}], "id": 1}'
+const DefaultJSONRPCPath = "/jsonrpc"
+
+// jsonRPCConn adapts a single HTTP request and response to the connection
+// expected by the JSON-RPC codec.
+type jsonRPCConn struct {
+	io.Reader
+	io.Writer
+}
+
+// Close is a no-op, as the HTTP server manages the underlying connection.
+func (c *jsonRPCConn) Close() error {
+	return nil
+}
+
+// jsonRPCHandler serves a JSON-RPC (version 1.0) request POSTed to it,
+// dispatching it to the given RPC server.
+func jsonRPCHandler(serv *rpc.Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "405 must POST a JSON-RPC request", http.StatusMethodNotAllowed)
+			return
+		}
+		log.Println("[DEBUG] daemon - JSON-RPC request")
+
+		w.Header().Set("Content-Type", "application/json")
+		err := serv.ServeRequest(jsonrpc.NewServerCodec(&jsonRPCConn{r.Body, w}))
+		if err != nil {
+			log.Println("[ERROR] daemon - unable to serve JSON-RPC request:", err)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/pact-foundation/pact-go/types"
+	"github.com/pact-foundation/pact-go/utils"
+)
+
+type jsonRPCResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  interface{}     `json:"error"`
+}
+
+func callJSONRPC(port int, body string, t *testing.T) jsonRPCResponse {
+	url := fmt.Sprintf("http://localhost:%d%s", port, DefaultJSONRPCPath)
+	res, err := http.Post(url, "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200 but got: %d", res.StatusCode)
+	}
+
+	var response jsonRPCResponse
+	if err = json.NewDecoder(res.Body).Decode(&response); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	return response
+}
+
+func TestJSONRPC_ListServers(t *testing.T) {
+	daemon, _ := createMockedDaemon(true)
+	port, _ := utils.GetFreePort()
+	defer waitForDaemonToShutdown(port, daemon, t)
+	go daemon.StartDaemon(port, "tcp", "")
+	connectToDaemon(port, t)
+
+	res := callJSONRPC(port, `{"method": "Daemon.ListServers", "params": [{}], "id": 1}`, t)
+	if res.Error != nil {
+		t.Fatalf("Error: %v", res.Error)
+	}
+	if res.ID != 1 {
+		t.Fatalf("Expected id 1 but got: %d", res.ID)
+	}
+
+	var list types.PactListResponse
+	if err := json.Unmarshal(res.Result, &list); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(list.Servers) != 3 {
+		t.Fatalf("Expected 3 servers to be listed, got: %d", len(list.Servers))
+	}
+}
+
+func TestJSONRPC_StartServer(t *testing.T) {
+	daemon, manager := createMockedDaemon(true)
+	port, _ := utils.GetFreePort()
+	defer waitForDaemonToShutdown(port, daemon, t)
+	go daemon.StartDaemon(port, "tcp", "")
+	connectToDaemon(port, t)
+
+	res := callJSONRPC(port, `{"method": "Daemon.StartServer", "params": [{"args": ["--port", "1234"]}], "id": 2}`, t)
+	if res.Error != nil {
+		t.Fatalf("Error: %v", res.Error)
+	}
+
+	var server types.MockServer
+	if err := json.Unmarshal(res.Result, &server); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if server.Pid == 0 || server.State != types.MockServerStarting {
+		t.Fatalf("Expected a starting server but got: %v", server)
+	}
+	if manager.ServiceStartCount != 1 {
+		t.Fatalf("Expected 1 server to be started but got: %d", manager.ServiceStartCount)
+	}
+}
+
+func TestJSONRPC_VerifyProviderError(t *testing.T) {
+	daemon, _ := createMockedDaemon(true)
+	port, _ := utils.GetFreePort()
+	defer waitForDaemonToShutdown(port, daemon, t)
+	go daemon.StartDaemon(port, "tcp", "")
+	connectToDaemon(port, t)
+
+	res := callJSONRPC(port, `{"method": "Daemon.VerifyProvider", "params": [{}], "id": 3}`, t)
+	if res.Error != "Pact URLs is mandatory" {
+		t.Fatalf("Expected validation error but got: %v", res.Error)
+	}
+}
+
+func TestJSONRPC_MethodNotAllowed(t *testing.T) {
+	daemon, _ := createMockedDaemon(true)
+	port, _ := utils.GetFreePort()
+	defer waitForDaemonToShutdown(port, daemon, t)
+	go daemon.StartDaemon(port, "tcp", "")
+	connectToDaemon(port, t)
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d%s", port, DefaultJSONRPCPath))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected status 405 but got: %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Lock describes a running Daemon, as recorded in its lock file.
+type Lock struct {
+	Pid     int    `json:"pid"`
+	Network string `json:"network"`
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+}
+
+// DefaultLockFile is the lock file of a Daemon started by `pact-go daemon`.
+func DefaultLockFile() string {
+	return filepath.Join(os.TempDir(), "pact-go", "daemon.lock")
+}
+
+// ReadLockFile reads the Lock of a Daemon from the given lock file. The Daemon
+// may no longer be running if it did not shut down cleanly.
+func ReadLockFile(path string) (*Lock, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lock Lock
+	if err = json.Unmarshal(contents, &lock); err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// writeLockFile records the Daemon of this process in the given lock file.
+func writeLockFile(path string, network string, address string, port int) error {
+	contents, err := json.Marshal(Lock{
+		Pid:     os.Getpid(),
+		Network: network,
+		Address: address,
+		Port:    port,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, contents, 0600)
+}
+
+// removeLockFile removes the given lock file, if it records the Daemon of
+// this process rather than one started since.
+func removeLockFile(path string) {
+	if lock, err := ReadLockFile(path); err == nil && lock.Pid == os.Getpid() {
+		os.Remove(path)
+	}
+}
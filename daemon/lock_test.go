@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pact-foundation/pact-go/utils"
+)
+
+func TestLockFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "locks", "daemon.lock")
+
+	if err := writeLockFile(path, "tcp", "localhost", 6666); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	lock, err := ReadLockFile(path)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	expected := Lock{Pid: os.Getpid(), Network: "tcp", Address: "localhost", Port: 6666}
+	if *lock != expected {
+		t.Fatalf("Expected lock %v but got: %v", expected, *lock)
+	}
+
+	removeLockFile(path)
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Expected lock file to be removed but got: %v", err)
+	}
+}
+
+func TestLockFile_OtherDaemon(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "daemon.lock")
+	ioutil.WriteFile(path, []byte(`{"pid": 1, "port": 6666}`), 0600)
+
+	removeLockFile(path)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected lock file of another daemon to be left alone but got: %v", err)
+	}
+}
+
+func TestStartDaemon_LockFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	daemon, _ := createMockedDaemon(true)
+	daemon.LockFile = filepath.Join(dir, "daemon.lock")
+	port, _ := utils.GetFreePort()
+	go daemon.StartDaemon(port, "tcp", "")
+	connectToDaemon(port, t)
+
+	lock, err := ReadLockFile(daemon.LockFile)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if lock.Port != port || lock.Network != "tcp" {
+		t.Fatalf("Expected lock file to record the daemon but got: %v", lock)
+	}
+
+	waitForDaemonToShutdown(port, daemon, t)
+	for i := 0; i < 10; i++ {
+		if _, err = os.Stat(daemon.LockFile); os.IsNotExist(err) {
+			return
+		}
+		<-time.After(50 * time.Millisecond)
+	}
+	t.Fatalf("Expected lock file to be removed on shutdown")
+}
@@ -1,15 +1,12 @@
 package daemon
 
-import (
-	"fmt"
-	"path/filepath"
-
-	"github.com/kardianos/osext"
-)
-
 // MockService is a wrapper for the Pact Mock Service.
 type MockService struct {
 	ServiceManager
+
+	// Path of the pact-mock-service binary. If empty, it is found as per
+	// FindTool.
+	Path string
 }
 
 // NewService creates a new MockService with default settings.
@@ -19,11 +16,13 @@ func (m *MockService) NewService(args []string) Service {
 	}
 	m.Args = append(m.Args, args...)
 
-	m.Cmd = getMockServiceCommandPath()
+	m.Cmd = m.Path
+	if m.Cmd == "" {
+		m.Cmd = getMockServiceCommandPath()
+	}
 	return m
 }
 
 func getMockServiceCommandPath() string {
-	dir, _ := osext.ExecutableFolder()
-	return fmt.Sprintf(filepath.Join(dir, "pact", "bin", "pact-mock-service"))
+	return toolPath(MockServiceTool)
 }
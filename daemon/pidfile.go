@@ -0,0 +1,101 @@
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pidfileExtension is the extension of the pidfiles kept in a StateDir.
+const pidfileExtension = ".pid"
+
+// pidfile returns the path of the pidfile for the given pid.
+func (s *ServiceManager) pidfile(pid int) string {
+	return filepath.Join(s.StateDir, fmt.Sprintf("%d%s", pid, pidfileExtension))
+}
+
+// writePidfile records a started process in the StateDir, along with the
+// service binary and the time it started, so that it can be reaped if the
+// Daemon dies. A process whose start time is unknown is not recorded, as it
+// could not be told apart from a later process reusing its pid.
+func (s *ServiceManager) writePidfile(pid int) {
+	if s.StateDir == "" {
+		return
+	}
+	started, err := processStartTime(pid)
+	if err != nil {
+		log.Println("[WARN] unable to record service, as its start time is unknown:", err)
+		return
+	}
+	if err := os.MkdirAll(s.StateDir, 0700); err != nil {
+		log.Println("[WARN] unable to create state directory:", err)
+		return
+	}
+
+	contents := fmt.Sprintf("%d\n%s\n%s\n", pid, s.Cmd, started)
+	if err := ioutil.WriteFile(s.pidfile(pid), []byte(contents), 0600); err != nil {
+		log.Println("[WARN] unable to write pidfile:", err)
+	}
+}
+
+// removePidfile removes the pidfile of a process that has exited.
+func (s *ServiceManager) removePidfile(pid int) {
+	if s.StateDir == "" {
+		return
+	}
+	os.Remove(s.pidfile(pid))
+}
+
+// reapOrphans kills the process groups of services recorded in the StateDir,
+// left behind by a Daemon that did not shut down cleanly. Processes that
+// can't be verified to be the recorded service, by their start time and
+// binary, are left alone, as their pid may have been reused.
+func (s *ServiceManager) reapOrphans() {
+	if s.StateDir == "" {
+		return
+	}
+	files, err := filepath.Glob(filepath.Join(s.StateDir, "*"+pidfileExtension))
+	if err != nil {
+		return
+	}
+
+	for _, file := range files {
+		pid, cmd, started, err := readPidfile(file)
+		if err != nil {
+			log.Printf("[WARN] ignoring invalid pidfile %s: %s\n", file, err)
+		} else if processRunning(pid) && processMatches(pid, cmd, started) {
+			log.Printf("[INFO] reaping orphaned service with pid %d (%s)\n", pid, cmd)
+			if err := killProcessGroup(pid); err != nil {
+				log.Printf("[WARN] unable to reap service with pid %d: %s\n", pid, err)
+			}
+		}
+		os.Remove(file)
+	}
+}
+
+// readPidfile returns the pid, service binary and start time recorded in a
+// pidfile.
+func readPidfile(file string) (int, string, string, error) {
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	lines := strings.SplitN(strings.TrimSpace(string(contents)), "\n", 3)
+	pid, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return 0, "", "", err
+	}
+	if pid <= 1 {
+		return 0, "", "", fmt.Errorf("invalid pid %d", pid)
+	}
+	if len(lines) != 3 {
+		return 0, "", "", fmt.Errorf("no service binary and start time recorded for pid %d", pid)
+	}
+
+	return pid, lines[1], lines[2], nil
+}
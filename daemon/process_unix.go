@@ -0,0 +1,119 @@
+//go:build !windows
+// +build !windows
+
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// setProcessGroup starts the command in a new process group, so that it and
+// any children it spawns can be killed together.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the process group led by the given pid. The pids
+// with special meaning to kill, of init and every process, are refused.
+func killProcessGroup(pid int) error {
+	if pid <= 1 {
+		return fmt.Errorf("refusing to kill process group %d", pid)
+	}
+	return syscall.Kill(-pid, syscall.SIGKILL)
+}
+
+// reapProcessGroup kills any processes left in the process group led by the
+// given pid, once its leader has exited and been waited on. A process running
+// with the pid has reused it, and may lead an unrelated process group of the
+// same id, so the group is then left alone, as it is if it no longer exists
+// or can't be signalled.
+func reapProcessGroup(pid int) {
+	if processRunning(pid) {
+		log.Printf("[DEBUG] not reaping process group %d, as its pid has been reused\n", pid)
+		return
+	}
+	if err := syscall.Kill(-pid, 0); err != nil {
+		return
+	}
+	killProcessGroup(pid)
+}
+
+// processRunning reports whether a process with the given pid exists.
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// interruptProcessGroup interrupts the process group led by the given pid,
+// so that any children of the service are asked to shut down with it.
+func interruptProcessGroup(pid int) error {
+	if pid <= 1 {
+		return fmt.Errorf("refusing to interrupt process group %d", pid)
+	}
+	return syscall.Kill(-pid, syscall.SIGINT)
+}
+
+// processStartTime returns the time the process with the given pid started,
+// from /proc where it is available or else from ps, which together with the
+// pid identifies the process.
+func processStartTime(pid int) (string, error) {
+	stat, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err == nil {
+		// The fields after the command, which may contain spaces, from the
+		// state onwards. The start time is the 22nd field of the stat.
+		fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+		if len(fields) < 20 {
+			return "", fmt.Errorf("unable to parse the stat of pid %d", pid)
+		}
+		return fields[19], nil
+	}
+	if _, statErr := os.Stat("/proc/self/stat"); statErr == nil {
+		return "", err
+	}
+
+	out, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", err
+	}
+	started := strings.TrimSpace(string(out))
+	if started == "" {
+		return "", fmt.Errorf("no process with pid %d", pid)
+	}
+	return started, nil
+}
+
+// processCommand returns the command line of the process with the given pid.
+func processCommand(pid int) (string, error) {
+	cmdline, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/cmdline")
+	if err == nil {
+		return string(bytes.Replace(cmdline, []byte{0}, []byte{' '}, -1)), nil
+	}
+
+	out, err := exec.Command("ps", "-o", "command=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// processMatches reports whether the process with the given pid is the one
+// recorded as started at the given time, running the given binary. If the
+// process can't be identified, it is assumed not to be.
+func processMatches(pid int, binary string, started string) bool {
+	if started == "" {
+		return false
+	}
+	if s, err := processStartTime(pid); err != nil || s != started {
+		return false
+	}
+	cmdline, err := processCommand(pid)
+	return err == nil && strings.Contains(cmdline, binary)
+}
@@ -0,0 +1,81 @@
+//go:build windows
+// +build windows
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// setProcessGroup starts the command in a new process group, so that it and
+// any children it spawns can be killed together.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// killProcessGroup kills the process with the given pid, and its children.
+// The pids of system processes are refused.
+func killProcessGroup(pid int) error {
+	if pid <= 4 {
+		return fmt.Errorf("refusing to kill process %d", pid)
+	}
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(pid)).Run()
+}
+
+// reapProcessGroup is a no-op, as the children of a process that has exited
+// can no longer be found by its pid.
+func reapProcessGroup(pid int) {}
+
+// processRunning reports whether a process with the given pid exists.
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
+
+// interruptProcessGroup interrupts the process with the given pid. Windows
+// has no interrupt signal for a process group, so this fails and the process
+// group is killed once the service has had time to exit.
+func interruptProcessGroup(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	defer p.Release()
+	return p.Signal(os.Interrupt)
+}
+
+// processStartTime returns the creation time of the process with the given
+// pid, which together with the pid identifies the process.
+func processStartTime(pid int) (string, error) {
+	h, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return "", err
+	}
+	defer syscall.CloseHandle(h)
+
+	var creation, exit, kernel, user syscall.Filetime
+	if err = syscall.GetProcessTimes(h, &creation, &exit, &kernel, &user); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(creation.Nanoseconds(), 10), nil
+}
+
+// processMatches reports whether the process with the given pid is the one
+// recorded as started at the given time. If the process can't be
+// identified, it is assumed not to be. The command line of other processes
+// is not available, so the binary is not checked.
+func processMatches(pid int, binary string, started string) bool {
+	if started == "" {
+		return false
+	}
+	s, err := processStartTime(pid)
+	return err == nil && s == started
+}
@@ -0,0 +1,177 @@
+package daemon
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pact-foundation/pact-go/utils"
+)
+
+// createCertificate writes a self-signed certificate for localhost, and its
+// key, to the given directory.
+func createCertificate(dir string, t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+
+	return certFile, keyFile
+}
+
+func TestAuthenticate(t *testing.T) {
+	handler := authenticate("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	testCases := map[string]int{
+		"":              http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"secret":        http.StatusUnauthorized,
+		"Bearer secret": http.StatusOK,
+	}
+
+	for header, status := range testCases {
+		req := httptest.NewRequest("POST", DefaultJSONRPCPath, nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		if res.Code != status {
+			t.Fatalf("Expected status %d for '%s' but got: %d", status, header, res.Code)
+		}
+	}
+}
+
+func TestAuthenticate_NoToken(t *testing.T) {
+	handler := authenticate("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("POST", DefaultJSONRPCPath, nil))
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 but got: %d", res.Code)
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	certFile, keyFile := createCertificate(dir, t)
+
+	config, err := NewTLSConfig(certFile, keyFile, "")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(config.Certificates) != 1 || config.ClientAuth != tls.NoClientCert {
+		t.Fatalf("Expected a server certificate and no client authentication")
+	}
+
+	config, err = NewTLSConfig(certFile, keyFile, certFile)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if config.ClientAuth != tls.RequireAndVerifyClientCert || config.ClientCAs == nil {
+		t.Fatalf("Expected client certificates to be required")
+	}
+}
+
+func TestNewTLSConfig_Fail(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	certFile, keyFile := createCertificate(dir, t)
+
+	testCases := [][]string{
+		{"", keyFile, ""},
+		{certFile, "", ""},
+		{filepath.Join(dir, "missing.pem"), keyFile, ""},
+		{certFile, keyFile, filepath.Join(dir, "missing.pem")},
+		{certFile, keyFile, keyFile},
+	}
+
+	for _, c := range testCases {
+		if _, err := NewTLSConfig(c[0], c[1], c[2]); err == nil {
+			t.Fatalf("Expected error for %v but got none", c)
+		}
+	}
+}
+
+func TestStartDaemon_TLSAndToken(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	certFile, keyFile := createCertificate(dir, t)
+
+	daemon, _ := createMockedDaemon(true)
+	daemon.Token = "secret"
+	daemon.TLSConfig, _ = NewTLSConfig(certFile, keyFile, "")
+	port, _ := utils.GetFreePort()
+	defer waitForDaemonToShutdown(port, daemon, t)
+	go daemon.StartDaemon(port, "tcp", "")
+	connectToDaemon(port, t)
+
+	pool := x509.NewCertPool()
+	ca, _ := ioutil.ReadFile(certFile)
+	pool.AppendCertsFromPEM(ca)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	url := fmt.Sprintf("https://localhost:%d%s", port, DefaultJSONRPCPath)
+
+	for token, status := range map[string]int{"": http.StatusUnauthorized, "secret": http.StatusOK} {
+		req, _ := http.NewRequest("POST", url, strings.NewReader(`{"method": "Daemon.ListServers", "params": [{}], "id": 1}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != status {
+			t.Fatalf("Expected status %d but got: %d", status, res.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"sync"
+
+	"github.com/pact-foundation/pact-go/types"
+)
+
+// maxServerEvents is the number of Mock Server events a Daemon holds for
+// clients to poll, dropping the oldest beyond it.
+const maxServerEvents = 1000
+
+// serverEvents records the lifecycle events of the Mock Servers of a Daemon,
+// so that clients can poll for them with the ServerEvents RPC.
+type serverEvents struct {
+	mutex  sync.Mutex
+	seq    int
+	events []types.MockServerEvent
+}
+
+// record receives the events of the service until the channel is closed.
+func (e *serverEvents) record(events <-chan ServiceEvent) {
+	for event := range events {
+		e.mutex.Lock()
+		e.seq++
+		e.events = append(e.events, types.MockServerEvent{
+			Seq:      e.seq,
+			Type:     string(event.Type),
+			Pid:      event.Pid,
+			Code:     event.Code,
+			Restarts: event.Restarts,
+		})
+		if len(e.events) > maxServerEvents {
+			e.events = e.events[len(e.events)-maxServerEvents:]
+		}
+		e.mutex.Unlock()
+	}
+}
+
+// since returns the events after the given Seq, of the Mock Server with the
+// given pid if it is not 0.
+func (e *serverEvents) since(seq int, pid int) []types.MockServerEvent {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	events := []types.MockServerEvent{}
+	for _, event := range e.events {
+		if event.Seq > seq && (pid == 0 || event.Pid == pid) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
@@ -10,9 +10,12 @@ import (
 type Service interface {
 	Setup()
 	Stop(pid int) (bool, error)
+	Exited(pid int) (bool, int)
+	Logs(pid int) ([]string, error)
 	List() map[int]*exec.Cmd
 	Command() *exec.Cmd
-	Start() *exec.Cmd
+	Start() (*exec.Cmd, error)
 	Run(io.Writer) (*exec.Cmd, error)
 	NewService(args []string) Service
+	Subscribe() (<-chan ServiceEvent, func())
 }
@@ -0,0 +1,74 @@
+package daemon
+
+import "log"
+
+// ServiceEventType is the type of a lifecycle event of a Service.
+type ServiceEventType string
+
+const (
+	// ServiceStarted is published when a service is started, or restarted.
+	ServiceStarted ServiceEventType = "started"
+
+	// ServiceStopped is published when a service exits after being stopped.
+	ServiceStopped ServiceEventType = "stopped"
+
+	// ServiceExited is published when a service exits without being stopped.
+	ServiceExited ServiceEventType = "exited"
+)
+
+// eventBufferSize is the number of events buffered for each subscriber.
+const eventBufferSize = 32
+
+// ServiceEvent is a lifecycle event of a service started by a ServiceManager.
+type ServiceEvent struct {
+	Type ServiceEventType
+
+	// Pid identifies the service, by the pid it was first started with.
+	Pid int
+
+	// Code is the exit code of a stopped or exited service.
+	Code int
+
+	// Restarts is the number of times the service has been restarted.
+	Restarts int
+}
+
+// Subscribe returns a channel receiving the lifecycle events of the services
+// started from now on, and a function to unsubscribe, closing the channel.
+// Events are dropped if the subscriber falls too far behind. The Daemon
+// subscribes to its Mock Servers, serving their events to remote clients with
+// the ServerEvents RPC.
+func (s *ServiceManager) Subscribe() (<-chan ServiceEvent, func()) {
+	events := make(chan ServiceEvent, eventBufferSize)
+
+	s.mutex.Lock()
+	if s.subscribers == nil {
+		s.subscribers = make(map[chan ServiceEvent]struct{})
+	}
+	s.subscribers[events] = struct{}{}
+	s.mutex.Unlock()
+
+	unsubscribe := func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		if _, ok := s.subscribers[events]; ok {
+			delete(s.subscribers, events)
+			close(events)
+		}
+	}
+
+	return events, unsubscribe
+}
+
+// publishLocked sends the event to all subscribers, without blocking. The
+// mutex must be held.
+func (s *ServiceManager) publishLocked(event ServiceEvent) {
+	for events := range s.subscribers {
+		select {
+		case events <- event:
+		default:
+			log.Printf("[WARN] dropping %s event for service with pid %d, subscriber is not keeping up\n", event.Type, event.Pid)
+		}
+	}
+}
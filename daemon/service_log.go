@@ -0,0 +1,119 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+// serviceLogLines is the number of lines of output kept for each service.
+const serviceLogLines = 500
+
+// serviceLog captures the output of a service, keeping the most recent lines
+// in memory and, optionally, all of it in a file.
+type serviceLog struct {
+	mutex sync.Mutex
+	lines []string
+	next  int
+	file  *os.File
+}
+
+// openFile writes the output captured so far, and all further output, to the
+// given file.
+func (l *serviceLog) openFile(path string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		log.Println("[WARN] unable to create log directory:", err)
+		return
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		log.Println("[WARN] unable to create service log file:", err)
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, line := range l.linesLocked() {
+		fmt.Fprintln(file, line)
+	}
+	l.file = file
+}
+
+// add records a line of output.
+func (l *serviceLog) add(line string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if len(l.lines) < serviceLogLines {
+		l.lines = append(l.lines, line)
+	} else {
+		l.lines[l.next] = line
+		l.next = (l.next + 1) % serviceLogLines
+	}
+
+	if l.file != nil {
+		fmt.Fprintln(l.file, line)
+	}
+}
+
+// Lines returns the most recent lines of output, oldest first.
+func (l *serviceLog) Lines() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.linesLocked()
+}
+
+// linesLocked is Lines, with the mutex held.
+func (l *serviceLog) linesLocked() []string {
+	lines := make([]string, 0, len(l.lines))
+	lines = append(lines, l.lines[l.next:]...)
+	return append(lines, l.lines[:l.next]...)
+}
+
+// close closes the log file, once the service will write no more output.
+func (l *serviceLog) close() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+}
+
+// logWriter splits the output of a process into lines, adding them to its
+// log and the Daemon log.
+type logWriter struct {
+	logs    *serviceLog
+	level   string
+	cmd     *exec.Cmd
+	partial []byte
+}
+
+// Write records each complete line written, buffering any partial line.
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(bytes.TrimRight(w.partial[:i], "\r"))
+		w.partial = w.partial[i+1:]
+
+		log.Printf("%s service %d: %s\n", w.level, w.cmd.Process.Pid, line)
+		w.logs.add(line)
+	}
+}
+
+// flush records any partial line left once the process has exited.
+func (w *logWriter) flush() {
+	if len(w.partial) > 0 {
+		w.Write([]byte("\n"))
+	}
+}
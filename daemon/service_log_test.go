@@ -0,0 +1,97 @@
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServiceLog_Lines(t *testing.T) {
+	l := &serviceLog{}
+	for i := 0; i < serviceLogLines+2; i++ {
+		l.add(fmt.Sprintf("line %d", i))
+	}
+
+	lines := l.Lines()
+	if len(lines) != serviceLogLines {
+		t.Fatalf("Expected %d lines but got: %d", serviceLogLines, len(lines))
+	}
+	if lines[0] != "line 2" || lines[len(lines)-1] != fmt.Sprintf("line %d", serviceLogLines+1) {
+		t.Fatalf("Expected the most recent lines, oldest first, but got: %s ... %s", lines[0], lines[len(lines)-1])
+	}
+}
+
+func TestServiceLog_File(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "logs", "1.log")
+
+	l := &serviceLog{}
+	l.add("before")
+	l.openFile(path)
+	l.add("after")
+	l.close()
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if string(contents) != "before\nafter\n" {
+		t.Fatalf("Expected all output in the log file but got: %s", contents)
+	}
+}
+
+func TestLogWriter(t *testing.T) {
+	l := &serviceLog{}
+	cmd := fakeExecCommand("", true, "")
+	cmd.Start()
+	defer cmd.Process.Kill()
+	w := &logWriter{logs: l, level: "[INFO]", cmd: cmd}
+
+	w.Write([]byte("one\r\ntw"))
+	w.Write([]byte("o\nthree"))
+	if !reflect.DeepEqual(l.Lines(), []string{"one", "two"}) {
+		t.Fatalf("Expected complete lines to be logged but got: %v", l.Lines())
+	}
+
+	w.flush()
+	if !reflect.DeepEqual(l.Lines(), []string{"one", "two", "three"}) {
+		t.Fatalf("Expected partial line to be flushed but got: %v", l.Lines())
+	}
+}
+
+func TestServiceManager_Logs(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	mgr := createServiceManager()
+	mgr.Env = []string{"GO_WANT_HELPER_PROCESS=1", "GO_WANT_HELPER_PROCESS_TO_SUCCEED=false"}
+	mgr.LogDir = dir
+
+	cmd, err := mgr.Start()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	pid := cmd.Process.Pid
+	waitForExit(mgr, pid, t)
+
+	lines, err := mgr.Logs(pid)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !reflect.DeepEqual(lines, []string{"COMMAND: oh noes!"}) {
+		t.Fatalf("Expected service output but got: %v", lines)
+	}
+
+	contents, _ := ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("%d.log", pid)))
+	if !strings.Contains(string(contents), "COMMAND: oh noes!") {
+		t.Fatalf("Expected service output in the log file but got: %s", contents)
+	}
+
+	if _, err := mgr.Logs(1234); err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+}
@@ -1,103 +1,323 @@
 package daemon
 
 import (
-	"bufio"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
+// RestartPolicy decides whether a service that exits without being stopped
+// is restarted.
+type RestartPolicy string
+
+const (
+	// RestartNever leaves crashed services exited. This is the default.
+	RestartNever RestartPolicy = "never"
+
+	// RestartOnFailure restarts services that exit with an error.
+	RestartOnFailure RestartPolicy = "on-failure"
+
+	// RestartAlways restarts services whenever they exit.
+	RestartAlways RestartPolicy = "always"
+)
+
+// outputWaitDelay is how long output is read for once a process has exited,
+// as children left behind may hold its output open.
+const outputWaitDelay = time.Second
+
+// defaultMaxRestarts is used when ServiceManager.MaxRestarts is not set.
+const defaultMaxRestarts = 3
+
 // ServiceManager is the default implementation of the Service interface.
+// It is safe for concurrent use.
 type ServiceManager struct {
-	Cmd                 string
-	processes           map[int]*exec.Cmd
-	Args                []string
-	Env                 []string
-	commandCompleteChan chan *exec.Cmd
-	commandCreatedChan  chan *exec.Cmd
+	Cmd  string
+	Args []string
+	Env  []string
+
+	// StateDir is where a pidfile is kept for each running service. Services
+	// left behind by a Daemon that crashed are reaped from it on Setup.
+	// No pidfiles are kept if it is empty.
+	StateDir string
+
+	// RestartPolicy decides whether a service that crashes is restarted, with
+	// the same arguments, up to MaxRestarts (default 3) times. A restarted
+	// service keeps the pid it was first started with as its identifier.
+	RestartPolicy RestartPolicy
+	MaxRestarts   int
+
+	// LogDir, if set, is where the output of each service is written, to a
+	// file named after its pid. The most recent output is always kept in
+	// memory, see Logs.
+	LogDir string
+
+	// mutex guards the registry of running services, the current process and
+	// exit record of each started service, and the event subscribers.
+	mutex       sync.Mutex
+	processes   map[int]*exec.Cmd
+	exits       map[int]*processExit
+	subscribers map[chan ServiceEvent]struct{}
 }
 
-// Setup the Management services.
-func (s *ServiceManager) Setup() {
-	log.Println("[DEBUG] setting up a service manager")
-	s.commandCreatedChan = make(chan *exec.Cmd)
-	s.commandCompleteChan = make(chan *exec.Cmd)
-	s.processes = make(map[int]*exec.Cmd)
+// processExit is closed once a process has exited, recording the error
+// returned from waiting on it.
+type processExit struct {
+	cmd      *exec.Cmd
+	done     chan struct{}
+	err      error
+	code     int
+	stopped  bool
+	restarts int
+
+	// logs captures the output of the service.
+	logs *serviceLog
 
-	// Listen for service create/kill
-	go s.addServiceMonitor()
-	go s.removeServiceMonitor()
+	// output are the read ends of the pipes the process writes its output
+	// to, copied to its logs until copied is closed.
+	output []*os.File
+	copied chan struct{}
 }
 
-// addServiceMonitor watches a channel to add services into operation.
-func (s *ServiceManager) addServiceMonitor() {
-	log.Println("[DEBUG] starting service creation monitor")
-	for {
-		select {
-		case p := <-s.commandCreatedChan:
-			if p != nil && p.Process != nil {
-				s.processes[p.Process.Pid] = p
-			}
-		}
+// copyOutput copies the output of the process to its logs, closing copied
+// once all of it has been read.
+func (e *processExit) copyOutput(writers []*logWriter) {
+	var wg sync.WaitGroup
+	for i, r := range e.output {
+		wg.Add(1)
+		go func(r *os.File, w *logWriter) {
+			defer wg.Done()
+			io.Copy(w, r)
+			w.flush()
+		}(r, writers[i])
 	}
+	wg.Wait()
+	close(e.copied)
 }
 
-// removeServiceMonitor watches a channel to remove services from operation.
-func (s *ServiceManager) removeServiceMonitor() {
-	log.Println("[DEBUG] starting service removal monitor")
-	var p *exec.Cmd
-	for {
-		select {
-		case p = <-s.commandCompleteChan:
-			if p != nil && p.Process != nil {
-				p.Process.Signal(os.Interrupt)
-				delete(s.processes, p.Process.Pid)
-			}
-		}
+// closeOutput waits up to outputWaitDelay for the output of a process that
+// has exited to be copied, then closes the pipes so that any children still
+// holding them open can't keep the output from being waited on.
+func (e *processExit) closeOutput() {
+	select {
+	case <-e.copied:
+	case <-time.After(outputWaitDelay):
+		log.Printf("[WARN] output of service with pid %d still open after it exited\n", e.cmd.Process.Pid)
+	}
+	for _, r := range e.output {
+		r.Close()
 	}
 }
 
-// Stop a Service and returns the exit status.
+// Setup the Management services, reaping any services orphaned in StateDir.
+func (s *ServiceManager) Setup() {
+	log.Println("[DEBUG] setting up a service manager")
+	s.mutex.Lock()
+	s.processes = make(map[int]*exec.Cmd)
+	s.exits = make(map[int]*processExit)
+	s.mutex.Unlock()
+
+	s.reapOrphans()
+}
+
+// Stop a Service and returns the exit status. The process group of the
+// Service is killed once it has stopped, or if it takes too long to stop.
 func (s *ServiceManager) Stop(pid int) (bool, error) {
 	log.Println("[DEBUG] stopping service with pid", pid)
+
+	// Remove service from registry, preventing it being restarted
+	s.mutex.Lock()
 	cmd := s.processes[pid]
+	if cmd == nil || cmd.Process == nil {
+		s.mutex.Unlock()
+		return false, fmt.Errorf("no service running with pid %d", pid)
+	}
+	exit := s.exitLocked(pid, cmd)
+	exit.stopped = true
+	cmd = exit.cmd
+	delete(s.processes, pid)
+	s.mutex.Unlock()
 
-	// Remove service from registry
-	go func() {
-		s.commandCompleteChan <- cmd
-	}()
+	if err := interruptProcessGroup(cmd.Process.Pid); err != nil {
+		cmd.Process.Signal(os.Interrupt)
+	}
 
 	// Wait for error, kill if it takes too long
-	var err error
-	done := make(chan error, 1)
-	go func() {
-		done <- cmd.Wait()
-	}()
-
 	select {
 	case <-time.After(3 * time.Second):
-		if err = cmd.Process.Kill(); err != nil {
-			log.Println("[ERROR] timeout reached, killing pid", pid)
-
-			return false, err
+		log.Println("[ERROR] timeout reached, killing pid", pid)
+		killProcessGroup(cmd.Process.Pid)
+		select {
+		case <-time.After(3 * time.Second):
+			return false, fmt.Errorf("unable to kill service with pid %d", pid)
+		case <-exit.done:
 		}
-	case err = <-done:
-		if err != nil {
-			log.Println("[ERROR] error waiting for process to complete", err)
-			return false, err
+	case <-exit.done:
+		if exit.err != nil {
+			log.Println("[ERROR] error waiting for process to complete", exit.err)
+			return false, exit.err
 		}
 	}
 
 	return true, nil
 }
 
-// List all Service PIDs.
+// exitLocked returns the exit record of the service with the given pid,
+// waiting on its process in the background if it is not yet being waited
+// on. The mutex must be held.
+func (s *ServiceManager) exitLocked(pid int, cmd *exec.Cmd) *processExit {
+	if s.exits == nil {
+		s.exits = make(map[int]*processExit)
+	}
+	if exit, ok := s.exits[pid]; ok {
+		return exit
+	}
+
+	exit := &processExit{cmd: cmd, done: make(chan struct{})}
+	s.exits[pid] = exit
+	go s.wait(pid, exit)
+
+	return exit
+}
+
+// wait waits for the process of a service to exit, cleaning up after it and
+// restarting the service if the RestartPolicy allows.
+func (s *ServiceManager) wait(pid int, exit *processExit) {
+	err := exit.cmd.Wait()
+	code := exitCode(exit.cmd)
+
+	// Leave no children of the process behind
+	reapProcessGroup(exit.cmd.Process.Pid)
+	exit.closeOutput()
+	s.removePidfile(exit.cmd.Process.Pid)
+
+	s.mutex.Lock()
+	exit.err = err
+	exit.code = code
+	event := ServiceEvent{Type: ServiceStopped, Pid: pid, Code: code, Restarts: exit.restarts}
+	if !exit.stopped {
+		if err != nil {
+			log.Printf("[WARN] service with pid %d exited: %s\n", pid, err)
+		}
+		event.Type = ServiceExited
+	}
+	s.publishLocked(event)
+	restarted := !exit.stopped && s.shouldRestart(exit) && s.restartLocked(pid, exit)
+	if !restarted && exit.logs != nil {
+		exit.logs.close()
+	}
+	s.mutex.Unlock()
+
+	close(exit.done)
+}
+
+// shouldRestart reports whether the RestartPolicy allows the service that
+// has exited to be restarted.
+func (s *ServiceManager) shouldRestart(exit *processExit) bool {
+	max := s.MaxRestarts
+	if max <= 0 {
+		max = defaultMaxRestarts
+	}
+	if exit.restarts >= max {
+		return false
+	}
+
+	switch s.RestartPolicy {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return exit.err != nil
+	default:
+		return false
+	}
+}
+
+// restartLocked starts a new process in place of the exited process of the
+// service with the given pid, reporting whether it could be started. The
+// mutex must be held.
+func (s *ServiceManager) restartLocked(pid int, exit *processExit) bool {
+	log.Printf("[INFO] restarting service with pid %d (restart %d)\n", pid, exit.restarts+1)
+	next, err := s.start(exit.cmd.Args[1:], exit.logs)
+	if err != nil {
+		log.Printf("[ERROR] unable to restart service with pid %d: %s\n", pid, err)
+		return false
+	}
+
+	next.restarts = exit.restarts + 1
+	s.exits[pid] = next
+	go s.wait(pid, next)
+
+	if _, ok := s.processes[pid]; ok {
+		s.processes[pid] = next.cmd
+	}
+	s.publishLocked(ServiceEvent{Type: ServiceStarted, Pid: pid, Restarts: next.restarts})
+	return true
+}
+
+// Exited reports whether the Service with the given pid has exited, and if so
+// its exit code. The exit code is -1 if the process was killed by a signal.
+// A Service that is restarted is not reported as exited.
+func (s *ServiceManager) Exited(pid int) (bool, int) {
+	s.mutex.Lock()
+	exit, ok := s.exits[pid]
+	s.mutex.Unlock()
+
+	if !ok {
+		return false, 0
+	}
+
+	select {
+	case <-exit.done:
+		return true, exit.code
+	default:
+		return false, 0
+	}
+}
+
+// Logs returns the most recent lines of output of the Service with the given
+// pid, including the output of any previous processes if it was restarted.
+func (s *ServiceManager) Logs(pid int) ([]string, error) {
+	s.mutex.Lock()
+	exit, ok := s.exits[pid]
+	s.mutex.Unlock()
+
+	if !ok || exit.logs == nil {
+		return nil, fmt.Errorf("no logs for service with pid %d", pid)
+	}
+	return exit.logs.Lines(), nil
+}
+
+// exitCode returns the exit code of a process that has been waited on.
+func exitCode(cmd *exec.Cmd) int {
+	if cmd.ProcessState == nil {
+		return -1
+	}
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok {
+		return status.ExitStatus()
+	}
+	if cmd.ProcessState.Success() {
+		return 0
+	}
+	return 1
+}
+
+// List all Services, by the pid they were first started with.
 func (s *ServiceManager) List() map[int]*exec.Cmd {
 	log.Println("[DEBUG] listing services")
-	return s.processes
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	processes := make(map[int]*exec.Cmd, len(s.processes))
+	for pid, cmd := range s.processes {
+		processes[pid] = cmd
+	}
+	return processes
 }
 
 // Run runs a service synchronously and log its output to the given Pipe.
@@ -119,46 +339,95 @@ func (s *ServiceManager) Command() *exec.Cmd {
 	return cmd
 }
 
-// Start a Service and log its output.
-func (s *ServiceManager) Start() *exec.Cmd {
+// Start a Service and log its output. An error is returned if the service
+// binary cannot be found or the process cannot be started.
+func (s *ServiceManager) Start() (*exec.Cmd, error) {
 	log.Println("[DEBUG] starting service")
-	cmd := exec.Command(s.Cmd, s.Args...)
-	cmd.Env = s.Env
-
-	cmdReader, err := cmd.StdoutPipe()
+	exit, err := s.start(s.Args, nil)
 	if err != nil {
-		log.Printf("[ERROR] unable to create output pipe for cmd: %s\n", err.Error())
-		os.Exit(1)
+		return nil, err
 	}
+	cmd := exit.cmd
+	pid := cmd.Process.Pid
 
-	cmdReaderErr, err := cmd.StderrPipe()
-	if err != nil {
-		log.Printf("[ERROR] unable to create error pipe for cmd: %s\n", err.Error())
-		os.Exit(1)
+	// Add service to registry, tracking the process exit so that a process
+	// that dies can be reported
+	s.mutex.Lock()
+	if s.processes == nil {
+		s.processes = make(map[int]*exec.Cmd)
+	}
+	if s.exits == nil {
+		s.exits = make(map[int]*processExit)
 	}
+	s.processes[pid] = cmd
+	s.exits[pid] = exit
+	go s.wait(pid, exit)
+	s.publishLocked(ServiceEvent{Type: ServiceStarted, Pid: pid})
+	s.mutex.Unlock()
 
-	scanner := bufio.NewScanner(cmdReader)
-	go func() {
-		for scanner.Scan() {
-			log.Printf("[INFO] %s\n", scanner.Text())
-		}
-	}()
+	return cmd, nil
+}
+
+// start starts a process of the service with the given arguments, in its
+// own process group, capturing its output in the given log or, if nil, a new
+// log. It returns the exit record of the process, to be waited on.
+func (s *ServiceManager) start(args []string, logs *serviceLog) (*processExit, error) {
+	if _, err := exec.LookPath(s.Cmd); err != nil {
+		log.Printf("[ERROR] unable to find service binary: %s\n", err.Error())
+		return nil, fmt.Errorf("unable to find service binary %s: %s", s.Cmd, err)
+	}
 
-	scanner2 := bufio.NewScanner(cmdReaderErr)
-	go func() {
-		for scanner2.Scan() {
-			log.Printf("[ERROR] service: %s\n", scanner2.Text())
+	cmd := exec.Command(s.Cmd, args...)
+	cmd.Env = s.Env
+	setProcessGroup(cmd)
+
+	newLog := logs == nil
+	if newLog {
+		logs = &serviceLog{}
+	}
+	exit := &processExit{cmd: cmd, done: make(chan struct{}), logs: logs, copied: make(chan struct{})}
+	writers := []*logWriter{
+		{logs: logs, level: "[INFO]", cmd: cmd},
+		{logs: logs, level: "[ERROR]", cmd: cmd},
+	}
+
+	// The output is copied from pipes, rather than by Wait, so that children
+	// holding it open once the process exits don't keep Wait from returning
+	var pipes []*os.File
+	for range writers {
+		r, w, err := os.Pipe()
+		if err != nil {
+			closeFiles(exit.output)
+			closeFiles(pipes)
+			return nil, fmt.Errorf("unable to start service %s: %s", s.Cmd, err)
 		}
-	}()
+		exit.output = append(exit.output, r)
+		pipes = append(pipes, w)
+	}
+	cmd.Stdout = pipes[0]
+	cmd.Stderr = pipes[1]
 
-	err = cmd.Start()
+	err := cmd.Start()
+	closeFiles(pipes)
 	if err != nil {
+		closeFiles(exit.output)
 		log.Println("[ERROR] service", err.Error())
-		os.Exit(1)
+		return nil, fmt.Errorf("unable to start service %s: %s", s.Cmd, err)
 	}
+	go exit.copyOutput(writers)
 
-	// Add service to registry
-	s.commandCreatedChan <- cmd
+	pid := cmd.Process.Pid
+	s.writePidfile(pid)
+	if newLog && s.LogDir != "" {
+		logs.openFile(filepath.Join(s.LogDir, fmt.Sprintf("%d.log", pid)))
+	}
 
-	return cmd
+	return exit, nil
+}
+
+// closeFiles closes each of the files.
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
 }
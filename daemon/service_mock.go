@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"os/exec"
@@ -16,10 +17,17 @@ type ServiceMock struct {
 	ServiceList         map[int]*exec.Cmd
 	ServiceStartCmd     *exec.Cmd
 	ServiceStartCount   int
+	ServiceStartError   error
 	ServicePort         int
 	ServiceStopCount    int
+	ServiceExitCodes    map[int]int
+	ServiceLogs         map[int][]string
 	ServicesSetupCalled bool
 
+	// ServiceEvents receive the events published by Start and Stop, one
+	// channel for each subscriber.
+	ServiceEvents []chan ServiceEvent
+
 	// ExecFunc sets the function to run when starting commands
 	ExecFunc func() *exec.Cmd
 }
@@ -32,9 +40,25 @@ func (s *ServiceMock) Setup() {
 // Stop a Service and returns the exit status.
 func (s *ServiceMock) Stop(pid int) (bool, error) {
 	s.ServiceStopCount++
+	s.publish(ServiceEvent{Type: ServiceStopped, Pid: pid})
 	return s.ServiceStopResult, s.ServiceStopError
 }
 
+// Exited reports the exit code of the given pid, if set in ServiceExitCodes.
+func (s *ServiceMock) Exited(pid int) (bool, int) {
+	code, ok := s.ServiceExitCodes[pid]
+	return ok, code
+}
+
+// Logs returns the lines of the given pid set in ServiceLogs.
+func (s *ServiceMock) Logs(pid int) ([]string, error) {
+	lines, ok := s.ServiceLogs[pid]
+	if !ok {
+		return nil, fmt.Errorf("no logs for service with pid %d", pid)
+	}
+	return lines, nil
+}
+
 // List all Service PIDs.
 func (s *ServiceMock) List() map[int]*exec.Cmd {
 	return s.ServiceList
@@ -52,17 +76,21 @@ func (s *ServiceMock) Run(w io.Writer) (*exec.Cmd, error) {
 }
 
 // Start a Service and log its output.
-func (s *ServiceMock) Start() *exec.Cmd {
-
+func (s *ServiceMock) Start() (*exec.Cmd, error) {
 	s.ServiceStartCount++
+	if s.ServiceStartError != nil {
+		return nil, s.ServiceStartError
+	}
+
 	cmd := s.ExecFunc()
 	cmd.Start()
 	if s.processes == nil {
 		s.processes = make(map[int]*exec.Cmd)
 	}
 	s.processes[cmd.Process.Pid] = cmd
+	s.publish(ServiceEvent{Type: ServiceStarted, Pid: cmd.Process.Pid})
 
-	return cmd
+	return cmd, nil
 }
 
 func (s *ServiceMock) Command() *exec.Cmd {
@@ -71,5 +99,24 @@ func (s *ServiceMock) Command() *exec.Cmd {
 
 // NewService creates a new MockService with default settings.
 func (s *ServiceMock) NewService(args []string) Service {
+	s.Args = args
 	return s
 }
+
+// Subscribe adds a channel to the ServiceEvents, returning it and a function
+// that does nothing.
+func (s *ServiceMock) Subscribe() (<-chan ServiceEvent, func()) {
+	events := make(chan ServiceEvent, eventBufferSize)
+	s.ServiceEvents = append(s.ServiceEvents, events)
+	return events, func() {}
+}
+
+// publish sends the event to the ServiceEvents channels, without blocking.
+func (s *ServiceMock) publish(event ServiceEvent) {
+	for _, events := range s.ServiceEvents {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
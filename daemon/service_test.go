@@ -2,9 +2,12 @@ package daemon
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
@@ -35,16 +38,16 @@ func TestServiceManager(t *testing.T) {
 func TestServiceManager_Setup(t *testing.T) {
 	mgr := createServiceManager()
 
-	if mgr.commandCompleteChan == nil {
-		t.Fatalf("Expected commandCompleteChan to be non-nil but got nil")
+	if mgr.processes == nil {
+		t.Fatalf("Expected processes to be non-nil but got nil")
 	}
 
-	if mgr.commandCreatedChan == nil {
-		t.Fatalf("Expected commandCreatedChan to be non-nil but got nil")
+	if mgr.exits == nil {
+		t.Fatalf("Expected exits to be non-nil but got nil")
 	}
 }
 
-func TestServiceManager_removeServiceMonitor(t *testing.T) {
+func TestServiceManager_Stop(t *testing.T) {
 	mgr := createServiceManager()
 	cmd := fakeExecCommand("", true, "")
 	cmd.Start()
@@ -52,126 +55,397 @@ func TestServiceManager_removeServiceMonitor(t *testing.T) {
 		cmd.Process.Pid: cmd,
 	}
 
-	mgr.commandCompleteChan <- cmd
+	mgr.Stop(cmd.Process.Pid)
 	var timeout = time.After(channelTimeout)
 	for {
 		select {
 		case <-time.After(10 * time.Millisecond):
-			if len(mgr.processes) == 0 {
+			if len(mgr.List()) == 0 {
 				return
 			}
 		case <-timeout:
-			if len(mgr.processes) != 0 {
-				t.Fatalf(`Expected 1 command to be removed from the queue. Have %d
-          Timed out after 500millis`, len(mgr.processes))
+			if len(mgr.List()) != 0 {
+				t.Fatalf(`Expected 1 command to be removed from the queue.
+          Timed out after 500millis`)
 			}
+			return
 		}
 	}
 }
 
-func TestServiceManager_addServiceMonitor(t *testing.T) {
+func TestServiceManager_List(t *testing.T) {
 	mgr := createServiceManager()
 	cmd := fakeExecCommand("", true, "")
 	cmd.Start()
-	mgr.commandCreatedChan <- cmd
-	var timeout = time.After(channelTimeout)
+	processes := map[int]*exec.Cmd{
+		cmd.Process.Pid: cmd,
+	}
+	mgr.processes = processes
 
-	for {
-		select {
-		case <-time.After(10 * time.Millisecond):
-			if len(mgr.processes) == 1 {
+	if !reflect.DeepEqual(processes, mgr.List()) {
+		t.Fatalf("Expected mgr.List() to equal processes")
+	}
+}
+
+func TestServiceManager_Concurrent(t *testing.T) {
+	mgr := createServiceManager()
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd, err := mgr.Start()
+			if err != nil {
+				t.Errorf("Error: %v", err)
 				return
 			}
-		case <-timeout:
-			if len(mgr.processes) != 1 {
-				t.Fatalf(`Expected 1 command to be added to the queue, but got: %d.
-          Timed out after 500millis`, len(mgr.processes))
+			mgr.List()
+			mgr.Exited(cmd.Process.Pid)
+			if _, err := mgr.Stop(cmd.Process.Pid); err != nil && err.Error() != "signal: interrupt" {
+				t.Errorf("Error: %v", err)
 			}
-			return
-		}
+		}()
+	}
+	wg.Wait()
+
+	if len(mgr.List()) != 0 {
+		t.Fatalf("Expected all services to be stopped, but got: %d", len(mgr.List()))
 	}
 }
 
-func TestServiceManager_addServiceMonitorWithDeadJob(t *testing.T) {
+// nextEvent returns the next event received, failing the test if there is
+// none within a second.
+func nextEvent(events <-chan ServiceEvent, t *testing.T) ServiceEvent {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(1 * time.Second):
+		t.Fatalf("Expected an event but got none")
+	}
+	return ServiceEvent{}
+}
+
+func TestServiceManager_Subscribe(t *testing.T) {
 	mgr := createServiceManager()
-	cmd := fakeExecCommand("", true, "")
-	mgr.commandCreatedChan <- cmd
-	var timeout = time.After(channelTimeout)
+	events, unsubscribe := mgr.Subscribe()
+	defer unsubscribe()
 
-	for {
-		select {
-		case <-time.After(10 * time.Millisecond):
-			if len(mgr.processes) != 0 {
-				t.Fatalf(`Expected 0 command to be added to the queue, but got: %d.
-          Timed out after 5 attempts`, len(mgr.processes))
-			}
-		case <-timeout:
-			if len(mgr.processes) != 0 {
-				t.Fatalf(`Expected 0 command to be added to the queue, but got: %d.
-				Timed out after 50millis`, len(mgr.processes))
-			}
-			return
+	cmd, err := mgr.Start()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	pid := cmd.Process.Pid
+
+	if event := nextEvent(events, t); event.Type != ServiceStarted || event.Pid != pid {
+		t.Fatalf("Expected started event but got: %v", event)
+	}
+
+	mgr.Stop(pid)
+	if event := nextEvent(events, t); event.Type != ServiceStopped || event.Pid != pid {
+		t.Fatalf("Expected stopped event but got: %v", event)
+	}
+}
+
+func TestServiceManager_SubscribeExited(t *testing.T) {
+	mgr := createServiceManager()
+	mgr.Env = []string{"GO_WANT_HELPER_PROCESS=1", "GO_WANT_HELPER_PROCESS_TO_SUCCEED=false"}
+	mgr.RestartPolicy = RestartOnFailure
+	mgr.MaxRestarts = 1
+	events, unsubscribe := mgr.Subscribe()
+	defer unsubscribe()
+
+	cmd, err := mgr.Start()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	pid := cmd.Process.Pid
+
+	expected := []ServiceEvent{
+		{Type: ServiceStarted, Pid: pid},
+		{Type: ServiceExited, Pid: pid, Code: 1},
+		{Type: ServiceStarted, Pid: pid, Restarts: 1},
+		{Type: ServiceExited, Pid: pid, Code: 1, Restarts: 1},
+	}
+	for _, e := range expected {
+		if event := nextEvent(events, t); event != e {
+			t.Fatalf("Expected event %v but got: %v", e, event)
 		}
 	}
 }
 
-func TestServiceManager_Stop(t *testing.T) {
+func TestServiceManager_Unsubscribe(t *testing.T) {
 	mgr := createServiceManager()
-	cmd := fakeExecCommand("", true, "")
-	cmd.Start()
-	mgr.processes = map[int]*exec.Cmd{
-		cmd.Process.Pid: cmd,
+	events, unsubscribe := mgr.Subscribe()
+	unsubscribe()
+	unsubscribe()
+
+	if _, ok := <-events; ok {
+		t.Fatalf("Expected events channel to be closed")
 	}
+	if len(mgr.subscribers) != 0 {
+		t.Fatalf("Expected no subscribers but got: %d", len(mgr.subscribers))
+	}
+}
 
-	mgr.Stop(cmd.Process.Pid)
+func TestServiceManager_Start(t *testing.T) {
+	mgr := createServiceManager()
+	mgr.Start()
 	var timeout = time.After(channelTimeout)
+
 	for {
 		select {
 		case <-time.After(10 * time.Millisecond):
-			if len(mgr.processes) == 0 {
+			if len(mgr.List()) == 1 {
 				return
 			}
 		case <-timeout:
-			if len(mgr.processes) != 0 {
-				t.Fatalf(`Expected 1 command to be removed from the queue.
-          Timed out after 500millis`)
+			if len(mgr.List()) != 1 {
+				t.Fatalf(`Expected 1 command to be added to the queue, but got: %d.
+          Timed out after 500millis`, len(mgr.List()))
 			}
 			return
 		}
 	}
 }
 
-func TestServiceManager_List(t *testing.T) {
+func TestServiceManager_StartMissingBinary(t *testing.T) {
 	mgr := createServiceManager()
-	cmd := fakeExecCommand("", true, "")
-	cmd.Start()
-	processes := map[int]*exec.Cmd{
-		cmd.Process.Pid: cmd,
+	mgr.Cmd = "this-binary-does-not-exist"
+
+	cmd, err := mgr.Start()
+	if err == nil {
+		t.Fatalf("Expected error but got none")
 	}
-	mgr.processes = processes
+	if cmd != nil {
+		t.Fatalf("Expected no command to be started")
+	}
+}
 
-	if !reflect.DeepEqual(processes, mgr.List()) {
-		t.Fatalf("Expected mgr.List() to equal processes")
+func TestServiceManager_StopExited(t *testing.T) {
+	mgr := createServiceManager()
+	mgr.Env = []string{"GO_WANT_HELPER_PROCESS=1", "GO_WANT_HELPER_PROCESS_TO_SUCCEED=false"}
+	cmd, err := mgr.Start()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	<-time.After(500 * time.Millisecond)
+
+	exited, code := mgr.Exited(cmd.Process.Pid)
+	if !exited || code != 1 {
+		t.Fatalf("Expected process to have exited with code 1 but got: %t (%d)", exited, code)
+	}
+
+	success, err := mgr.Stop(cmd.Process.Pid)
+	if success || err == nil || err.Error() != "exit status 1" {
+		t.Fatalf("Expected exit status 1 but got: %v", err)
 	}
 }
 
-func TestServiceManager_Start(t *testing.T) {
+func TestServiceManager_ExitedRunning(t *testing.T) {
 	mgr := createServiceManager()
-	mgr.Start()
-	var timeout = time.After(channelTimeout)
+	cmd, err := mgr.Start()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer cmd.Process.Kill()
 
+	if exited, _ := mgr.Exited(cmd.Process.Pid); exited {
+		t.Fatalf("Expected process to be running")
+	}
+}
+
+func TestServiceManager_StopUnknown(t *testing.T) {
+	mgr := createServiceManager()
+
+	if _, err := mgr.Stop(1234); err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+}
+
+// waitForExit waits for the service with the given pid to exit.
+func waitForExit(mgr *ServiceManager, pid int, t *testing.T) {
+	timeout := time.After(3 * time.Second)
 	for {
 		select {
-		case <-time.After(10 * time.Millisecond):
-			if len(mgr.processes) == 1 {
-				return
-			}
 		case <-timeout:
-			if len(mgr.processes) != 1 {
-				t.Fatalf(`Expected 1 command to be added to the queue, but got: %d.
-          Timed out after 500millis`, len(mgr.processes))
+			t.Fatalf("Expected service %d to exit < 3s", pid)
+		case <-time.After(50 * time.Millisecond):
+			if exited, _ := mgr.Exited(pid); exited {
+				return
 			}
-			return
 		}
 	}
 }
+
+func TestServiceManager_StateDir(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	mgr := createServiceManager()
+	mgr.StateDir = dir
+
+	cmd, err := mgr.Start()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	pidfile := mgr.pidfile(cmd.Process.Pid)
+	if _, err = os.Stat(pidfile); err != nil {
+		t.Fatalf("Expected pidfile to be written but got: %v", err)
+	}
+
+	mgr.Stop(cmd.Process.Pid)
+	if _, err = os.Stat(pidfile); !os.IsNotExist(err) {
+		t.Fatalf("Expected pidfile to be removed but got: %v", err)
+	}
+}
+
+func TestServiceManager_ReapOrphans(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	orphaned := createServiceManager()
+	orphaned.StateDir = dir
+	orphaned.Env = append(orphaned.Env, "GO_HELPER_PROCESS_SLEEP=10s")
+
+	cmd, err := orphaned.Start()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer cmd.Process.Kill()
+
+	mgr := &ServiceManager{StateDir: dir}
+	mgr.Setup()
+	waitForExit(orphaned, cmd.Process.Pid, t)
+
+	if _, err = os.Stat(orphaned.pidfile(cmd.Process.Pid)); !os.IsNotExist(err) {
+		t.Fatalf("Expected pidfile to be removed but got: %v", err)
+	}
+}
+
+func TestServiceManager_ReapOrphansReusedPid(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	mgr := &ServiceManager{StateDir: dir, Cmd: "pact-mock-service"}
+	mgr.writePidfile(os.Getpid())
+
+	// The test process is not a mock service, so must not be killed
+	mgr.Setup()
+
+	if _, err := os.Stat(mgr.pidfile(os.Getpid())); !os.IsNotExist(err) {
+		t.Fatalf("Expected pidfile to be removed but got: %v", err)
+	}
+}
+
+func TestServiceManager_ReapOrphansReusedPidOfBinary(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	mgr := &ServiceManager{StateDir: dir, Cmd: os.Args[0]}
+	pidfile := mgr.pidfile(os.Getpid())
+	ioutil.WriteFile(pidfile, []byte(fmt.Sprintf("%d\n%s\n0\n", os.Getpid(), os.Args[0])), 0600)
+
+	// The test process runs the binary, but started at another time
+	mgr.Setup()
+
+	if _, err := os.Stat(pidfile); !os.IsNotExist(err) {
+		t.Fatalf("Expected pidfile to be removed but got: %v", err)
+	}
+}
+
+func TestProcessStartTime(t *testing.T) {
+	started, err := processStartTime(os.Getpid())
+	if err != nil || started == "" {
+		t.Fatalf("Expected the start time of the test process but got: %q, %v", started, err)
+	}
+	if !processMatches(os.Getpid(), os.Args[0], started) {
+		t.Fatalf("Expected the test process to match its start time and binary")
+	}
+	if processMatches(os.Getpid(), os.Args[0], "") {
+		t.Fatalf("Expected a process with no recorded start time not to match")
+	}
+}
+
+func TestServiceManager_ReapOrphansInvalidPid(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	pidfile := filepath.Join(dir, "1.pid")
+	ioutil.WriteFile(pidfile, []byte("1\npact-mock-service\n"), 0600)
+
+	if _, _, _, err := readPidfile(pidfile); err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+	if err := killProcessGroup(1); err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+
+	mgr := &ServiceManager{StateDir: dir}
+	mgr.Setup()
+	if _, err := os.Stat(pidfile); !os.IsNotExist(err) {
+		t.Fatalf("Expected invalid pidfile to be removed but got: %v", err)
+	}
+}
+
+func TestReapProcessGroup_PidReused(t *testing.T) {
+	// A running process leading its own group stands in for one that has
+	// reused the pid of a service that exited
+	cmd := exec.Command(os.Args[0], "-test.run=TestHelperProcess", "--")
+	cmd.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS=1", "GO_HELPER_PROCESS_SLEEP=5s")
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(done)
+	}()
+	defer func() {
+		killProcessGroup(cmd.Process.Pid)
+		<-done
+	}()
+
+	reapProcessGroup(cmd.Process.Pid)
+	select {
+	case <-done:
+		t.Fatalf("Expected the process group of a reused pid not to be killed")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestServiceManager_RestartOnFailure(t *testing.T) {
+	mgr := createServiceManager()
+	mgr.Env = []string{"GO_WANT_HELPER_PROCESS=1", "GO_WANT_HELPER_PROCESS_TO_SUCCEED=false"}
+	mgr.RestartPolicy = RestartOnFailure
+	mgr.MaxRestarts = 2
+
+	cmd, err := mgr.Start()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	pid := cmd.Process.Pid
+	waitForExit(mgr, pid, t)
+
+	if restarts := mgr.exits[pid].restarts; restarts != 2 {
+		t.Fatalf("Expected service to be restarted 2 times but got: %d", restarts)
+	}
+	if current := mgr.List()[pid]; current == nil || current.Process.Pid == pid {
+		t.Fatalf("Expected service to be listed with its restarted process but got: %v", current)
+	}
+}
+
+func TestServiceManager_RestartNotStopped(t *testing.T) {
+	mgr := createServiceManager()
+	mgr.RestartPolicy = RestartAlways
+
+	cmd, err := mgr.Start()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	pid := cmd.Process.Pid
+	mgr.Stop(pid)
+
+	if exited, _ := mgr.Exited(pid); !exited {
+		t.Fatalf("Expected stopped service not to be restarted")
+	}
+	if len(mgr.List()) != 0 {
+		t.Fatalf("Expected stopped service to be removed from the registry")
+	}
+}
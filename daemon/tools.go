@@ -0,0 +1,125 @@
+package daemon
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kardianos/osext"
+)
+
+// The pact tools run by the Daemon.
+const (
+	MockServiceTool = "pact-mock-service"
+	VerifierTool    = "pact-provider-verifier"
+)
+
+// minToolVersions are the oldest versions of the pact tools supported. Newer
+// versions are supported up to the next major version.
+var minToolVersions = map[string]string{
+	MockServiceTool: "2.1.0",
+	VerifierTool:    "1.11.0",
+}
+
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
+
+// FindTool returns the path of the named pact tool. It is, in order of
+// preference: the given path, the tool in binDir, the tool installed
+// alongside the pact-go executable (in pact/bin) or the tool on the PATH.
+func FindTool(name string, path string, binDir string) (string, error) {
+	if path != "" {
+		found, err := exec.LookPath(path)
+		if err != nil {
+			return "", fmt.Errorf("unable to find %s at %s: %s", name, path, err)
+		}
+		return found, nil
+	}
+
+	if binDir != "" {
+		found, err := exec.LookPath(filepath.Join(binDir, name))
+		if err != nil {
+			return "", fmt.Errorf("unable to find %s in %s: %s", name, binDir, err)
+		}
+		return found, nil
+	}
+
+	if found, err := exec.LookPath(bundledToolPath(name)); err == nil {
+		return found, nil
+	}
+	if found, err := exec.LookPath(name); err == nil {
+		return found, nil
+	}
+
+	return "", fmt.Errorf("unable to find %s in %s or on the PATH. Install the pact tools and set the pact bin directory (--pact-bin-dir or PACT_BIN_DIR), or add them to the PATH", name, filepath.Dir(bundledToolPath(name)))
+}
+
+// bundledToolPath returns the path of the named pact tool in the pact/bin
+// directory distributed alongside the pact-go executable.
+func bundledToolPath(name string) string {
+	dir, _ := osext.ExecutableFolder()
+	return filepath.Join(dir, "pact", "bin", name)
+}
+
+// toolPath returns the path of the named pact tool, found as per FindTool,
+// or the bundled path if it cannot be found so that errors refer to it.
+func toolPath(name string) string {
+	path, err := FindTool(name, "", "")
+	if err != nil {
+		return bundledToolPath(name)
+	}
+	return path
+}
+
+// CheckToolVersion runs the named pact tool at the given path to find its
+// version, returning an error if it is not supported by this version of
+// Pact Go.
+func CheckToolVersion(name string, path string) (string, error) {
+	out, err := exec.Command(path, "version").Output()
+	if err != nil {
+		return "", fmt.Errorf("unable to determine the version of %s (%s): %s", name, path, err)
+	}
+
+	version := versionPattern.FindString(string(out))
+	if version == "" {
+		return "", fmt.Errorf("unable to determine the version of %s (%s) from: %s", name, path, strings.TrimSpace(string(out)))
+	}
+
+	min := minToolVersions[name]
+	if min != "" && !compatibleVersion(version, min) {
+		return version, fmt.Errorf("%s %s (%s) is not supported, version %s or newer before %d.0.0 is required", name, version, path, min, parseVersion(min)[0]+1)
+	}
+
+	return version, nil
+}
+
+// compatibleVersion reports whether the version is at least min, with the
+// same major version.
+func compatibleVersion(version string, min string) bool {
+	v := parseVersion(version)
+	m := parseVersion(min)
+	if v[0] != m[0] {
+		return false
+	}
+	for i := 1; i < 3; i++ {
+		if v[i] != m[i] {
+			return v[i] > m[i]
+		}
+	}
+	return true
+}
+
+// parseVersion returns the major, minor and patch numbers of a version.
+func parseVersion(version string) [3]int {
+	var parts [3]int
+	match := versionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return parts
+	}
+	for i := range parts {
+		parts[i], _ = strconv.Atoi(match[i+1])
+	}
+	return parts
+}
@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// createTool creates a fake pact tool in dir that prints the given version.
+func createTool(dir string, name string, version string, t *testing.T) string {
+	path := filepath.Join(dir, name)
+	script := fmt.Sprintf("#!/bin/sh\necho %s\n", version)
+	if runtime.GOOS == "windows" {
+		path += ".bat"
+		script = fmt.Sprintf("@echo %s\r\n", version)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	return path
+}
+
+func TestFindTool(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	tool := createTool(dir, MockServiceTool, "2.6.4", t)
+
+	path, err := FindTool(MockServiceTool, tool, "")
+	if err != nil || path != tool {
+		t.Fatalf("Expected explicit path %s but got: %s (%v)", tool, path, err)
+	}
+
+	path, err = FindTool(MockServiceTool, "", dir)
+	if err != nil || path != tool {
+		t.Fatalf("Expected %s in the bin dir but got: %s (%v)", tool, path, err)
+	}
+
+	oldPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", oldPath)
+	os.Setenv("PATH", dir)
+	path, err = FindTool(MockServiceTool, "", "")
+	if err != nil || path != tool {
+		t.Fatalf("Expected %s on the PATH but got: %s (%v)", tool, path, err)
+	}
+}
+
+func TestFindTool_NotFound(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+
+	if _, err := FindTool(MockServiceTool, filepath.Join(dir, "missing"), ""); err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+
+	if _, err := FindTool(MockServiceTool, "", dir); err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+
+	oldPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", oldPath)
+	os.Setenv("PATH", dir)
+	_, err := FindTool(MockServiceTool, "", "")
+	if err == nil || !strings.Contains(err.Error(), "PACT_BIN_DIR") {
+		t.Fatalf("Expected error explaining how to configure the tools but got: %v", err)
+	}
+}
+
+func TestCheckToolVersion(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		version string
+		valid   bool
+	}{
+		{"2.1.0", true},
+		{"2.6.4", true},
+		{"2.0.9", false},
+		{"1.9.0", false},
+		{"3.0.0", false},
+	}
+	for _, c := range cases {
+		tool := createTool(dir, MockServiceTool, c.version, t)
+		version, err := CheckToolVersion(MockServiceTool, tool)
+		if c.valid && (err != nil || version != c.version) {
+			t.Fatalf("Expected version %s to be supported but got: %s (%v)", c.version, version, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Expected version %s not to be supported", c.version)
+		}
+	}
+}
+
+func TestCheckToolVersion_Invalid(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	tool := createTool(dir, VerifierTool, "unknown", t)
+
+	if _, err := CheckToolVersion(VerifierTool, tool); err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+
+	if _, err := CheckToolVersion(VerifierTool, filepath.Join(dir, "missing")); err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+}
@@ -1,16 +1,16 @@
 package daemon
 
 import (
-	"fmt"
 	"log"
-	"path/filepath"
-
-	"github.com/kardianos/osext"
 )
 
 // VerificationService is a wrapper for the Pact Provider Verifier Service.
 type VerificationService struct {
 	ServiceManager
+
+	// Path of the pact-provider-verifier binary. If empty, it is found as per
+	// FindTool.
+	Path string
 }
 
 // NewService creates a new VerificationService with default settings.
@@ -30,11 +30,13 @@ func (v *VerificationService) NewService(args []string) Service {
 	log.Printf("[DEBUG] starting verification service with args: %v\n", args)
 
 	v.Args = args
-	v.Cmd = getVerifierCommandPath()
+	v.Cmd = v.Path
+	if v.Cmd == "" {
+		v.Cmd = getVerifierCommandPath()
+	}
 	return v
 }
 
 func getVerifierCommandPath() string {
-	dir, _ := osext.ExecutableFolder()
-	return fmt.Sprintf(filepath.Join(dir, "pact", "bin", "pact-provider-verifier"))
+	return toolPath(VerifierTool)
 }
@@ -1,6 +1,7 @@
 package dsl
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -47,7 +48,7 @@ type HalDoc struct {
 //   1. Ask for all 'latest' consumers
 //   2. Pass a set of tags (e.g. 'latest' and 'prod') and find all consumers
 //      that match
-func findConsumers(provider string, request *types.VerifyRequest) error {
+func findConsumers(ctx context.Context, provider string, request *types.VerifyRequest) error {
 	log.Println("[DEBUG] broker - find consumers for provider:", provider)
 
 	client := &http.Client{}
@@ -69,6 +70,7 @@ func findConsumers(provider string, request *types.VerifyRequest) error {
 			return err
 		}
 
+		req = req.WithContext(ctx)
 		req.Header.Set("Accept", "application/hal+json")
 
 		if request.BrokerUsername != "" && request.BrokerPassword != "" {
@@ -102,6 +104,10 @@ func findConsumers(provider string, request *types.VerifyRequest) error {
 		}
 
 		for _, p := range doc.Links.Pacts {
+			if !consumerSelected(request.FilterConsumers, p.Name) {
+				log.Println("[DEBUG] broker - skipping pact for filtered consumer:", p.Name)
+				continue
+			}
 			pactURLs[p.Title] = p.Href
 		}
 	}
@@ -1,6 +1,7 @@
 package dsl
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -17,7 +18,7 @@ func TestPact_findConsumersNoTags(t *testing.T) {
 	request := types.VerifyRequest{
 		BrokerURL: s.URL,
 	}
-	err := findConsumers("bobby", &request)
+	err := findConsumers(context.Background(), "bobby", &request)
 	if err != nil {
 		t.Fatalf("Error: %s", err.Error())
 	}
@@ -39,7 +40,7 @@ func TestPact_findConsumersWithTags(t *testing.T) {
 		Tags:      []string{"dev", "prod"},
 		BrokerURL: s.URL,
 	}
-	err := findConsumers("bobby", &request)
+	err := findConsumers(context.Background(), "bobby", &request)
 	if err != nil {
 		t.Fatalf("Error: %s", err.Error())
 	}
@@ -60,7 +61,7 @@ func TestPact_findConsumersBrokerDown(t *testing.T) {
 		Tags:      []string{"dev", "prod"},
 		BrokerURL: fmt.Sprintf("http://localhost:%d", port),
 	}
-	err := findConsumers("idontexist", &request)
+	err := findConsumers(context.Background(), "idontexist", &request)
 
 	if err == nil {
 		t.Fatalf("Expected error but got none")
@@ -74,7 +75,7 @@ func TestPact_findConsumersInvalidResponse(t *testing.T) {
 		Tags:      []string{"broken"},
 		BrokerURL: s.URL,
 	}
-	err := findConsumers("bobby", &request)
+	err := findConsumers(context.Background(), "bobby", &request)
 
 	if err == nil {
 		t.Fatalf("Expected error but got none")
@@ -85,7 +86,7 @@ func TestPact_findConsumersInvalidURL(t *testing.T) {
 	request := types.VerifyRequest{
 		BrokerURL: "%%%",
 	}
-	err := findConsumers("broken", &request)
+	err := findConsumers(context.Background(), "broken", &request)
 
 	if err == nil {
 		t.Fatalf("Expected error but got none")
@@ -99,7 +100,7 @@ func TestPact_findConsumersErrorResponse(t *testing.T) {
 		Tags:      []string{"dev"},
 		BrokerURL: s.URL,
 	}
-	err := findConsumers("broken", &request)
+	err := findConsumers(context.Background(), "broken", &request)
 
 	if err == nil {
 		t.Fatalf("Expected error but got none")
@@ -113,7 +114,7 @@ func TestPact_findConsumersNoConsumers(t *testing.T) {
 		Tags:      []string{"dev", "prod"},
 		BrokerURL: s.URL,
 	}
-	err := findConsumers("idontexist", &request)
+	err := findConsumers(context.Background(), "idontexist", &request)
 	if err == nil {
 		t.Fatalf("Expected error but got none")
 	}
@@ -128,7 +129,7 @@ func TestPact_findConsumersAuthenticated(t *testing.T) {
 		BrokerUsername: "foo",
 		BrokerPassword: "bar",
 	}
-	err := findConsumers("bobby", &request)
+	err := findConsumers(context.Background(), "bobby", &request)
 	if err != nil {
 		t.Fatalf("Error: %s", err)
 	}
@@ -141,7 +142,7 @@ func TestPact_findConsumersAuthenticatedFail(t *testing.T) {
 		Tags:      []string{"dev", "prod"},
 		BrokerURL: s.URL,
 	}
-	err := findConsumers("bobby", &request)
+	err := findConsumers(context.Background(), "bobby", &request)
 
 	switch err {
 	case ErrUnauthorized:
@@ -1,11 +1,20 @@
 package dsl
 
 import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"net/http"
 	"net/rpc"
 	"net/url"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -21,11 +30,20 @@ var (
 	commandVerifyProvider = "Daemon.VerifyProvider"
 	commandListServers    = "Daemon.ListServers"
 	commandStopDaemon     = "Daemon.StopDaemon"
+	commandCancelVerify   = "Daemon.CancelVerification"
+	commandServerStatus   = "Daemon.ServerStatus"
+	commandServerLogs     = "Daemon.ServerLogs"
+	commandServerEvents   = "Daemon.ServerEvents"
 )
 
 // Client is the simplified remote interface to the Pact Daemon.
 type Client interface {
-	StartServer() *types.MockServer
+	StartServer(args []string, port int) (*types.MockServer, error)
+	StopServer(server *types.MockServer) (*types.MockServer, error)
+	ListServers() (*types.PactListResponse, error)
+	ServerStatus(server *types.MockServer) (*types.MockServer, error)
+	ServerLogs(server *types.MockServer) (*types.MockServer, error)
+	ServerEvents(request types.MockServerEventsRequest) ([]types.MockServerEvent, error)
 }
 
 // PactClient is the default implementation of the Client interface.
@@ -38,6 +56,19 @@ type PactClient struct {
 
 	// Address the Daemon is listening on
 	Address string
+
+	// SocketPath is the unix domain socket the Daemon is listening on. If set,
+	// it is used instead of the Port, Network and Address.
+	// Defaults to the PACT_DAEMON_SOCKET environment variable.
+	SocketPath string
+
+	// Token to present to the Daemon, if it requires one.
+	// Defaults to the PACT_DAEMON_TOKEN environment variable.
+	Token string
+
+	// TLSConfig to connect to the Daemon with, if it is served over TLS.
+	// Include a client certificate if the Daemon requires one.
+	TLSConfig *tls.Config
 }
 
 // Get a port given a URL
@@ -59,79 +90,335 @@ func getPort(rawURL string) int {
 	return -1
 }
 
-func getHTTPClient(port int, network string, address string) (*rpc.Client, error) {
+func (p *PactClient) getHTTPClient(ctx context.Context) (*rpc.Client, error) {
 	log.Println("[DEBUG] creating an HTTP client")
-	err := waitForPort(port, network, address, fmt.Sprintf(`Timed out waiting for Daemon on port %d - are you
-		sure it's running?`, port))
+	network, address := p.getDaemonAddress()
+	location := fmt.Sprintf("port %d", p.Port)
+	if isUnixNetwork(network) {
+		location = fmt.Sprintf("socket %s", address)
+	}
+	err := waitForPort(ctx, p.Port, network, address, fmt.Sprintf(`Timed out waiting for Daemon on %s - are you
+		sure it's running?`, location))
+	if err != nil {
+		return nil, err
+	}
+	return p.dialHTTP(ctx, network, dialAddress(network, address, p.Port))
+}
+
+// dialHTTP connects to an RPC server at the given address, in the same way
+// as rpc.DialHTTP but honouring the context whilst connecting, and using the
+// token and TLS configuration of the client.
+func (p *PactClient) dialHTTP(ctx context.Context, network string, address string) (*rpc.Client, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, address)
 	if err != nil {
 		return nil, err
 	}
-	return rpc.DialHTTP(network, fmt.Sprintf("%s:%d", address, port))
+
+	if p.TLSConfig != nil {
+		config := p.TLSConfig.Clone()
+		if config.ServerName == "" {
+			config.ServerName = strings.Trim(p.Address, "[]")
+			if config.ServerName == "" {
+				config.ServerName = "localhost"
+			}
+		}
+		tlsConn := tls.Client(conn, config)
+		if deadline, ok := ctx.Deadline(); ok {
+			tlsConn.SetDeadline(deadline)
+		}
+		if err = tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn.SetDeadline(time.Time{})
+		conn = tlsConn
+	}
+
+	token := p.Token
+	if token == "" {
+		token = os.Getenv("PACT_DAEMON_TOKEN")
+	}
+	connect := fmt.Sprintf("CONNECT %s HTTP/1.0\n", rpc.DefaultRPCPath)
+	if token != "" {
+		connect = fmt.Sprintf("%sAuthorization: Bearer %s\n", connect, token)
+	}
+	io.WriteString(conn, connect+"\n")
+	res, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: "CONNECT"})
+	if err == nil && res.Status == "200 Connected to Go RPC" {
+		return rpc.NewClient(conn), nil
+	}
+	if err == nil && res.StatusCode == http.StatusUnauthorized {
+		err = errors.New("the Daemon rejected the token, check PactClient.Token")
+	} else if err == nil {
+		err = errors.New("unexpected HTTP response: " + res.Status)
+	}
+	conn.Close()
+
+	return nil, &net.OpError{
+		Op:   "dial-http",
+		Net:  network + " " + address,
+		Addr: nil,
+		Err:  err,
+	}
+}
+
+// callRPC invokes the named RPC function, returning early if the context is
+// done before the call completes.
+func callRPC(ctx context.Context, client *rpc.Client, method string, args interface{}, reply interface{}) error {
+	c := client.Go(method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c = <-c.Done:
+		return c.Error
+	}
 }
 
 // Use this to wait for a daemon to be running prior
-// to running tests.
-var waitForPort = func(port int, network string, address string, message string) error {
+// to running tests. Waits for at most timeoutDuration, unless the context
+// has a deadline.
+var waitForPort = func(ctx context.Context, port int, network string, address string, message string) error {
 	log.Println("[DEBUG] waiting for port", port, "to become available")
 	timeout := time.After(timeoutDuration)
+	if _, ok := ctx.Deadline(); ok {
+		timeout = nil
+	}
 
 	for {
 		select {
+		case <-ctx.Done():
+			log.Printf("[ERROR] %s waiting for server to start. %s", ctx.Err(), message)
+			return fmt.Errorf("%s waiting for server to start. %s", ctx.Err(), message)
 		case <-timeout:
 			log.Printf("[ERROR] Expected server to start < %s. %s", timeoutDuration, message)
 			return fmt.Errorf("Expected server to start < %s. %s", timeoutDuration, message)
 		case <-time.After(50 * time.Millisecond):
-			_, err := net.Dial(network, fmt.Sprintf("%s:%d", address, port))
+			conn, err := net.Dial(network, dialAddress(network, address, port))
 			if err == nil {
+				conn.Close()
 				return nil
 			}
 		}
 	}
 }
 
-// StartServer starts a remote Pact Mock Server.
-func (p *PactClient) StartServer(args []string, port int) *types.MockServer {
+// StartServer starts a remote Pact Mock Server, returning an error if it
+// could not be started or does not start listening on the given port. If the
+// port is 0, the Daemon allocates a free port.
+func (p *PactClient) StartServer(args []string, port int) (*types.MockServer, error) {
+	return p.StartServerContext(context.Background(), args, port)
+}
+
+// StartServerContext starts a remote Pact Mock Server, waiting until the
+// context is done for it to start.
+func (p *PactClient) StartServerContext(ctx context.Context, args []string, port int) (*types.MockServer, error) {
+	if port != 0 {
+		args = append(args, []string{"--port", strconv.Itoa(port)}...)
+	}
+	return p.startServer(ctx, types.MockServer{Args: args, Port: port})
+}
+
+// StartServerInRange starts a remote Pact Mock Server on a port allocated by
+// the Daemon from the given ports, a list of ports and ranges of ports such
+// as "8081,8085-8090".
+func (p *PactClient) StartServerInRange(args []string, ports string) (*types.MockServer, error) {
+	return p.StartServerInRangeContext(context.Background(), args, ports)
+}
+
+// StartServerInRangeContext starts a remote Pact Mock Server on a port
+// allocated from the given ports, waiting until the context is done for it
+// to start.
+func (p *PactClient) StartServerInRangeContext(ctx context.Context, args []string, ports string) (*types.MockServer, error) {
+	return p.startServer(ctx, types.MockServer{Args: args, AllowedPorts: ports})
+}
+
+// startServer asks the Daemon to start a Mock Server, and waits for it to
+// start listening.
+func (p *PactClient) startServer(ctx context.Context, request types.MockServer) (*types.MockServer, error) {
 	log.Println("[DEBUG] client: starting a server")
 	var res types.MockServer
-	client, err := getHTTPClient(p.Port, p.getNetworkInterface(), p.Address)
+	client, err := p.getHTTPClient(ctx)
+	if err != nil {
+		return &res, err
+	}
+	defer client.Close()
+
+	network, address := p.getServerAddress()
+	request.Host = address
+	err = callRPC(ctx, client, commandStartServer, request, &res)
+	if err != nil {
+		log.Println("[ERROR] rpc:", err.Error())
+		return &res, err
+	}
+	if res.Port == 0 {
+		res.Port = request.Port
+	}
+
+	err = waitForPort(ctx, res.Port, network, address, fmt.Sprintf(`Timed out waiting for Mock Server to
+			start on port %d - are you sure it's running?`, res.Port))
+	if err != nil {
+		return &res, p.stopFailedServer(&res, err)
+	}
+
+	return &res, nil
+}
+
+// stopFailedServer stops a Mock Server that did not start properly, adding
+// the reason it failed (e.g. its exit code) to the error.
+func (p *PactClient) stopFailedServer(server *types.MockServer, err error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutDuration)
+	defer cancel()
+
+	status, serr := p.ServerStatusContext(ctx, server)
+	if serr == nil && status.State == types.MockServerExited {
+		err = fmt.Errorf("%s\nMock Server exited with code %d before it started listening on port %d", err, status.Status, server.Port)
+	}
+
+	stopped, serr := p.StopServerContext(ctx, server)
+	if serr == nil && stopped.Error != "" && status.State != types.MockServerExited {
+		err = fmt.Errorf("%s\nMock Server stopped: %s", err, stopped.Error)
+	}
+
+	return err
+}
+
+// ServerStatus returns the lifecycle state of a remote Pact Mock Server:
+// whether it is starting, ready or has exited (and its exit code).
+func (p *PactClient) ServerStatus(server *types.MockServer) (*types.MockServer, error) {
+	return p.ServerStatusContext(context.Background(), server)
+}
+
+// ServerStatusContext returns the lifecycle state of a remote Pact Mock Server.
+func (p *PactClient) ServerStatusContext(ctx context.Context, server *types.MockServer) (*types.MockServer, error) {
+	log.Println("[DEBUG] client: server status")
+	var res types.MockServer
+	client, err := p.getHTTPClient(ctx)
 	if err == nil {
-		args = append(args, []string{"--port", strconv.Itoa(port)}...)
-		err = client.Call(commandStartServer, types.MockServer{Args: args}, &res)
-		res.Port = port
+		defer client.Close()
+		err = callRPC(ctx, client, commandServerStatus, server, &res)
 		if err != nil {
 			log.Println("[ERROR] rpc:", err.Error())
 		}
 	}
+	return &res, err
+}
+
+// ServerLogs returns the lifecycle state of a remote Pact Mock Server, along
+// with its most recent output in Logs.
+func (p *PactClient) ServerLogs(server *types.MockServer) (*types.MockServer, error) {
+	return p.ServerLogsContext(context.Background(), server)
+}
 
+// ServerLogsContext returns the most recent output of a remote Pact Mock
+// Server.
+func (p *PactClient) ServerLogsContext(ctx context.Context, server *types.MockServer) (*types.MockServer, error) {
+	log.Println("[DEBUG] client: server logs")
+	var res types.MockServer
+	client, err := p.getHTTPClient(ctx)
 	if err == nil {
-		waitForPort(port, p.getNetworkInterface(), p.Address, fmt.Sprintf(`Timed out waiting for Mock Server to
-			start on port %d - are you sure it's running?`, port))
+		defer client.Close()
+		err = callRPC(ctx, client, commandServerLogs, server, &res)
+		if err != nil {
+			log.Println("[ERROR] rpc:", err.Error())
+		}
 	}
+	return &res, err
+}
+
+// ServerEvents returns the lifecycle events of the Mock Servers of a remote
+// Daemon after the Since event: when each is started or restarted, and when
+// it is stopped or exits. Poll it, with the Seq of the last event seen, to
+// follow Mock Servers shared with other clients.
+func (p *PactClient) ServerEvents(request types.MockServerEventsRequest) ([]types.MockServerEvent, error) {
+	return p.ServerEventsContext(context.Background(), request)
+}
 
-	return &res
+// ServerEventsContext returns the lifecycle events of the Mock Servers of a
+// remote Daemon.
+func (p *PactClient) ServerEventsContext(ctx context.Context, request types.MockServerEventsRequest) ([]types.MockServerEvent, error) {
+	log.Println("[DEBUG] client: server events")
+	var res []types.MockServerEvent
+	client, err := p.getHTTPClient(ctx)
+	if err == nil {
+		defer client.Close()
+		err = callRPC(ctx, client, commandServerEvents, request, &res)
+		if err != nil {
+			log.Println("[ERROR] rpc:", err.Error())
+		}
+	}
+	return res, err
 }
 
 // VerifyProvider runs the verification process against a running Provider.
 func (p *PactClient) VerifyProvider(request types.VerifyRequest) (types.ProviderVerifierResponse, error) {
+	return p.VerifyProviderContext(context.Background(), request)
+}
+
+// VerifyProviderContext runs the verification process against a running
+// Provider. If the context has a deadline, the verifier process is stopped by
+// the Daemon when it is reached. If the context is cancelled, the Daemon is
+// asked to stop the verifier process.
+func (p *PactClient) VerifyProviderContext(ctx context.Context, request types.VerifyRequest) (types.ProviderVerifierResponse, error) {
 	log.Println("[DEBUG] client: verifying a provider")
 
 	port := getPort(request.ProviderBaseURL)
 
-	waitForPort(port, p.getNetworkInterface(), p.Address, fmt.Sprintf(`Timed out waiting for Provider API to start
+	// If the Provider doesn't start in time the verifier reports the failure,
+	// but there is no point starting it once the context is done
+	network, address := p.getServerAddress()
+	err := waitForPort(ctx, port, network, address, fmt.Sprintf(`Timed out waiting for Provider API to start
 		 on port %d - are you sure it's running?`, port))
+	if err != nil && ctx.Err() != nil {
+		return types.ProviderVerifierResponse{}, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && request.Timeout == 0 {
+		request.Timeout = deadline.Sub(time.Now())
+	}
+	if request.VerificationID == "" {
+		request.VerificationID = newVerificationID()
+	}
 
 	var res types.ProviderVerifierResponse
-	client, err := getHTTPClient(p.Port, p.getNetworkInterface(), p.Address)
+	client, err := p.getHTTPClient(ctx)
 	if err == nil {
-		err = client.Call(commandVerifyProvider, request, &res)
+		defer client.Close()
+		err = callRPC(ctx, client, commandVerifyProvider, request, &res)
 		if err != nil {
 			log.Println("[ERROR] rpc: ", err.Error())
 		}
+		if ctx.Err() != nil {
+			p.cancelVerification(request.VerificationID)
+		}
 	}
 
 	return res, err
 }
 
+// cancelVerification asks the Daemon to stop a running verification.
+func (p *PactClient) cancelVerification(id string) {
+	log.Println("[DEBUG] client: cancelling verification", id)
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutDuration)
+	defer cancel()
+
+	var res string
+	client, err := p.getHTTPClient(ctx)
+	if err == nil {
+		defer client.Close()
+		err = callRPC(ctx, client, commandCancelVerify, id, &res)
+	}
+	if err != nil {
+		log.Println("[ERROR] rpc:", err.Error())
+	}
+}
+
+// newVerificationID creates a random identifier for a verification request.
+func newVerificationID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 // sanitiseRubyResponse removes Ruby-isms from the response content
 // making the output much more human readable
 func sanitiseRubyResponse(response string) string {
@@ -150,40 +437,60 @@ func sanitiseRubyResponse(response string) string {
 }
 
 // ListServers lists all running Pact Mock Servers.
-func (p *PactClient) ListServers() *types.PactListResponse {
+func (p *PactClient) ListServers() (*types.PactListResponse, error) {
+	return p.ListServersContext(context.Background())
+}
+
+// ListServersContext lists all running Pact Mock Servers.
+func (p *PactClient) ListServersContext(ctx context.Context) (*types.PactListResponse, error) {
 	log.Println("[DEBUG] client: listing servers")
 	var res types.PactListResponse
-	client, err := getHTTPClient(p.Port, p.getNetworkInterface(), p.Address)
+	client, err := p.getHTTPClient(ctx)
 	if err == nil {
-		err = client.Call(commandListServers, types.MockServer{}, &res)
+		defer client.Close()
+		err = callRPC(ctx, client, commandListServers, types.MockServer{}, &res)
 		if err != nil {
 			log.Println("[ERROR] rpc:", err.Error())
 		}
 	}
-	return &res
+	return &res, err
 }
 
-// StopServer stops a remote Pact Mock Server.
-func (p *PactClient) StopServer(server *types.MockServer) *types.MockServer {
+// StopServer stops a remote Pact Mock Server. An error is returned if the
+// Daemon could not be reached; if the Mock Server did not stop cleanly, its
+// Status is non-zero and Error contains the reason.
+func (p *PactClient) StopServer(server *types.MockServer) (*types.MockServer, error) {
+	return p.StopServerContext(context.Background(), server)
+}
+
+// StopServerContext stops a remote Pact Mock Server.
+func (p *PactClient) StopServerContext(ctx context.Context, server *types.MockServer) (*types.MockServer, error) {
 	log.Println("[DEBUG] client: stop server")
 	var res types.MockServer
-	client, err := getHTTPClient(p.Port, p.getNetworkInterface(), p.Address)
+	client, err := p.getHTTPClient(ctx)
 	if err == nil {
-		err = client.Call(commandStopServer, server, &res)
+		defer client.Close()
+		err = callRPC(ctx, client, commandStopServer, server, &res)
 		if err != nil {
 			log.Println("[ERROR] rpc:", err.Error())
 		}
 	}
-	return &res
+	return &res, err
 }
 
 // StopDaemon remotely shuts down the Pact Daemon.
 func (p *PactClient) StopDaemon() error {
+	return p.StopDaemonContext(context.Background())
+}
+
+// StopDaemonContext remotely shuts down the Pact Daemon.
+func (p *PactClient) StopDaemonContext(ctx context.Context) error {
 	log.Println("[DEBUG] client: stop daemon")
 	var req, res string
-	client, err := getHTTPClient(p.Port, p.getNetworkInterface(), p.Address)
+	client, err := p.getHTTPClient(ctx)
 	if err == nil {
-		err = client.Call(commandStopDaemon, &req, &res)
+		defer client.Close()
+		err = callRPC(ctx, client, commandStopDaemon, &req, &res)
 		if err != nil {
 			log.Println("[ERROR] rpc:", err.Error())
 		}
@@ -199,3 +506,39 @@ func (p *PactClient) getNetworkInterface() string {
 	}
 	return p.Network
 }
+
+// getDaemonAddress returns the network and address of the Daemon, which is
+// the SocketPath if one is specified.
+func (p *PactClient) getDaemonAddress() (string, string) {
+	socket := p.SocketPath
+	if socket == "" {
+		socket = os.Getenv("PACT_DAEMON_SOCKET")
+	}
+	if socket != "" {
+		return "unix", socket
+	}
+	return p.getNetworkInterface(), p.Address
+}
+
+// getServerAddress returns the network and address to reach the Mock Servers
+// and Provider on. These always listen on TCP, even if the Daemon does not.
+func (p *PactClient) getServerAddress() (string, string) {
+	if isUnixNetwork(p.getNetworkInterface()) {
+		return "tcp", "localhost"
+	}
+	return p.getNetworkInterface(), p.Address
+}
+
+// isUnixNetwork returns true for the unix domain socket networks.
+func isUnixNetwork(network string) bool {
+	return strings.HasPrefix(network, "unix")
+}
+
+// dialAddress returns the address to dial on the given network: the address
+// itself for a unix domain socket, or the address and port otherwise.
+func dialAddress(network string, address string, port int) string {
+	if isUnixNetwork(network) {
+		return address
+	}
+	return fmt.Sprintf("%s:%d", address, port)
+}
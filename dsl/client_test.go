@@ -1,12 +1,23 @@
 package dsl
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"math/big"
 	"net"
 	"net/rpc"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -57,11 +68,11 @@ func waitForDaemonToShutdown(port int, t *testing.T) {
 			t.Fatalf("Expected server to shutdown < 1s.")
 		case <-time.After(50 * time.Millisecond):
 			conn, err := net.Dial("tcp", fmt.Sprintf(":%d", port))
-			conn.SetReadDeadline(time.Now())
-			defer conn.Close()
 			if err != nil {
 				return
 			}
+			conn.SetReadDeadline(time.Now())
+			defer conn.Close()
 			buffer := make([]byte, 8)
 			_, err = conn.Read(buffer)
 			if err != nil {
@@ -78,6 +89,13 @@ func waitForDaemonToShutdown(port int, t *testing.T) {
 // Stubbing the exec.Cmd interface is hard, see fakeExec* functions for
 // the magic.
 func createDaemon(port int, success bool) (*daemon.Daemon, *daemon.ServiceMock) {
+	d, svc := newDaemon(success)
+	go d.StartDaemon(port, "tcp", "")
+	return d, svc
+}
+
+// newDaemon creates a Daemon as per createDaemon, without starting it.
+func newDaemon(success bool) (*daemon.Daemon, *daemon.ServiceMock) {
 	execFunc := fakeExecSuccessCommand
 	if !success {
 		execFunc = fakeExecFailCommand
@@ -108,9 +126,36 @@ func createDaemon(port int, success bool) (*daemon.Daemon, *daemon.ServiceMock)
 		}
 	}()
 
-	d := daemon.NewDaemon(svc, svc)
-	go d.StartDaemon(port, "tcp", "")
-	return d, svc
+	return daemon.NewDaemon(svc, svc), svc
+}
+
+// createCertificate creates a self-signed certificate for localhost, returning
+// it and a pool to trust it with.
+func createCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
 }
 
 func TestClient_List(t *testing.T) {
@@ -120,7 +165,10 @@ func TestClient_List(t *testing.T) {
 	defer waitForDaemonToShutdown(port, t)
 	client := &PactClient{Port: port}
 
-	s := client.ListServers()
+	s, err := client.ListServers()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
 
 	if len(s.Servers) != 3 {
 		t.Fatalf("Expected 3 server to be running, got %d", len(s.Servers))
@@ -130,9 +178,11 @@ func TestClient_List(t *testing.T) {
 func TestClient_ListFail(t *testing.T) {
 	timeoutDuration = 50 * time.Millisecond
 	client := &PactClient{ /* don't supply port */ }
-	client.StartServer([]string{}, 0)
-	list := client.ListServers()
+	list, err := client.ListServers()
 
+	if err == nil {
+		t.Fatalf("Expected error but got none")
+	}
 	if len(list.Servers) != 0 {
 		t.Fatalf("Expected 0 servers, got %d", len(list.Servers))
 	}
@@ -146,11 +196,315 @@ func TestClient_StartServer(t *testing.T) {
 	defer waitForDaemonToShutdown(port, t)
 	client := &PactClient{Port: port}
 
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	waitForPort = func(context.Context, int, string, string, string) error {
+		return nil
+	}
+
+	mport, _ := utils.GetFreePort()
+	server, err := client.StartServer([]string{}, mport)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if server.Port != mport {
+		t.Fatalf("Expected server to be started on port %d, got %d", mport, server.Port)
+	}
+	if svc.ServiceStartCount != 1 {
+		t.Fatalf("Expected 1 server to have been started, got %d", svc.ServiceStartCount)
+	}
+}
+
+func TestClient_StartServerInRange(t *testing.T) {
+	port, _ := utils.GetFreePort()
+	_, svc := createDaemon(port, true)
+	waitForPortInTest(port, t)
+	defer waitForDaemonToShutdown(port, t)
+	client := &PactClient{Port: port}
+
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	waitForPort = func(context.Context, int, string, string, string) error {
+		return nil
+	}
+
+	mport, _ := utils.GetFreePort()
+	server, err := client.StartServerInRange([]string{}, fmt.Sprintf("%d", mport))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if server.Port != mport {
+		t.Fatalf("Expected server to be started on port %d, got %d", mport, server.Port)
+	}
+
+	// The port stays reserved for the running server
+	_, err = client.StartServerInRange([]string{}, fmt.Sprintf("%d", mport))
+	if err == nil {
+		t.Fatalf("Expected error when no ports are free but got none")
+	}
+	if svc.ServiceStartCount != 1 {
+		t.Fatalf("Expected 1 server to have been started, got %d", svc.ServiceStartCount)
+	}
+}
+
+func TestClient_StartServerPortInUse(t *testing.T) {
+	port, _ := utils.GetFreePort()
+	_, svc := createDaemon(port, true)
+	waitForPortInTest(port, t)
+	defer waitForDaemonToShutdown(port, t)
+	client := &PactClient{Port: port}
+
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer l.Close()
+	mport := l.Addr().(*net.TCPAddr).Port
+
+	_, err = client.StartServer([]string{}, mport)
+	if err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Fatalf("Expected port in use error but got '%v'", err)
+	}
+	if svc.ServiceStartCount != 0 {
+		t.Fatalf("Expected no server to have been started, got %d", svc.ServiceStartCount)
+	}
+}
+
+func TestClient_StartServerMissingBinary(t *testing.T) {
+	port, _ := utils.GetFreePort()
+	_, svc := createDaemon(port, true)
+	waitForPortInTest(port, t)
+	defer waitForDaemonToShutdown(port, t)
+	client := &PactClient{Port: port}
+	svc.ServiceStartError = errors.New("unable to find service binary pact-mock-service")
+
+	mport, _ := utils.GetFreePort()
+	_, err := client.StartServer([]string{}, mport)
+	if err == nil || !strings.Contains(err.Error(), "unable to find service binary") {
+		t.Fatalf("Expected missing binary error but got '%v'", err)
+	}
+}
+
+func TestClient_StartServerNeverListens(t *testing.T) {
+	mport, _ := utils.GetFreePort()
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	waitForPort = func(ctx context.Context, port int, network string, address string, message string) error {
+		if port == mport {
+			return errors.New(message)
+		}
+		return old(ctx, port, network, address, message)
+	}
+
+	port, _ := utils.GetFreePort()
+	_, svc := createDaemon(port, true)
+	waitForPortInTest(port, t)
+	defer waitForDaemonToShutdown(port, t)
+	client := &PactClient{Port: port}
+	svc.ServiceStopResult = false
+	svc.ServiceStopError = errors.New("exit status 1")
+
+	_, err := client.StartServer([]string{}, mport)
+	if err == nil || !strings.Contains(err.Error(), "Mock Server stopped: exit status 1") {
+		t.Fatalf("Expected exit status in error but got '%v'", err)
+	}
+	if svc.ServiceStopCount != 1 {
+		t.Fatalf("Expected the failed server to have been stopped, got %d", svc.ServiceStopCount)
+	}
+}
+
+func TestClient_ServerStatus(t *testing.T) {
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	waitForPort = func(context.Context, int, string, string, string) error {
+		return nil
+	}
+
+	port, _ := utils.GetFreePort()
+	_, svc := createDaemon(port, true)
+	waitForPortInTest(port, t)
+	defer waitForDaemonToShutdown(port, t)
+	client := &PactClient{Port: port}
+
+	mport, _ := utils.GetFreePort()
+	server, err := client.StartServer([]string{}, mport)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	svc.ServiceExitCodes = map[int]int{server.Pid: 3}
+
+	status, err := client.ServerStatus(server)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if status.State != types.MockServerExited || status.Status != 3 {
+		t.Fatalf("Expected server to have exited with code 3 but got: %s (%d)", status.State, status.Status)
+	}
+}
+
+func TestClient_ServerLogs(t *testing.T) {
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	waitForPort = func(context.Context, int, string, string, string) error {
+		return nil
+	}
+
+	port, _ := utils.GetFreePort()
+	_, svc := createDaemon(port, true)
+	waitForPortInTest(port, t)
+	defer waitForDaemonToShutdown(port, t)
+	client := &PactClient{Port: port}
+
+	mport, _ := utils.GetFreePort()
+	server, err := client.StartServer([]string{}, mport)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	svc.ServiceLogs = map[int][]string{server.Pid: {"oh noes!"}}
+
+	res, err := client.ServerLogs(server)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(res.Logs) != 1 || res.Logs[0] != "oh noes!" {
+		t.Fatalf("Expected mock server logs but got: %v", res.Logs)
+	}
+}
+
+func TestClient_ServerEvents(t *testing.T) {
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	waitForPort = func(context.Context, int, string, string, string) error {
+		return nil
+	}
+
+	port, _ := utils.GetFreePort()
+	createDaemon(port, true)
+	waitForPortInTest(port, t)
+	defer waitForDaemonToShutdown(port, t)
+	client := &PactClient{Port: port}
+
 	mport, _ := utils.GetFreePort()
-	client.StartServer([]string{}, mport)
+	server, err := client.StartServer([]string{}, mport)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	timeout := time.After(3 * time.Second)
+	for {
+		events, err := client.ServerEvents(types.MockServerEventsRequest{Pid: server.Pid})
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if len(events) == 1 && events[0].Type == "started" {
+			break
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("Expected a started event but got: %v", events)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestClient_Token(t *testing.T) {
+	port, _ := utils.GetFreePort()
+	d, _ := newDaemon(true)
+	d.Token = "secret"
+	go d.StartDaemon(port, "tcp", "")
+	waitForPortInTest(port, t)
+	defer func() {
+		client := &PactClient{Port: port, Token: "secret"}
+		client.StopDaemon()
+	}()
+
+	client := &PactClient{Port: port, Token: "wrong"}
+	if _, err := client.ListServers(); err == nil || !strings.Contains(err.Error(), "rejected the token") {
+		t.Fatalf("Expected token to be rejected but got '%v'", err)
+	}
+
+	client.Token = "secret"
+	list, err := client.ListServers()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(list.Servers) != 3 {
+		t.Fatalf("Expected 3 server to be running, got %d", len(list.Servers))
+	}
+}
+
+func TestClient_TLS(t *testing.T) {
+	cert, pool := createCertificate(t)
+	port, _ := utils.GetFreePort()
+	d, _ := newDaemon(true)
+	d.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	go d.StartDaemon(port, "tcp", "")
+	waitForPortInTest(port, t)
+
+	client := &PactClient{Port: port, TLSConfig: &tls.Config{RootCAs: pool}}
+	if _, err := client.ListServers(); err == nil {
+		t.Fatalf("Expected connection without a client certificate to fail")
+	}
+
+	client.TLSConfig.Certificates = []tls.Certificate{cert}
+	list, err := client.ListServers()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(list.Servers) != 3 {
+		t.Fatalf("Expected 3 server to be running, got %d", len(list.Servers))
+	}
+
+	if err = client.StopDaemon(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+}
+
+func TestClient_SocketPath(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "daemon.sock")
+
+	d, svc := newDaemon(true)
+	go d.StartDaemon(0, "unix", socket)
+	client := &PactClient{SocketPath: socket}
+	defer client.StopDaemon()
+
+	list, err := client.ListServers()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(list.Servers) != 3 {
+		t.Fatalf("Expected 3 server to be running, got %d", len(list.Servers))
+	}
+
+	// The Mock Server itself is still reached over TCP
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	var addresses []string
+	waitForPort = func(ctx context.Context, port int, network string, address string, message string) error {
+		addresses = append(addresses, network+" "+address)
+		if network == "tcp" {
+			return nil
+		}
+		return old(ctx, port, network, address, message)
+	}
+
+	mport, _ := utils.GetFreePort()
+	if _, err = client.StartServer([]string{}, mport); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
 	if svc.ServiceStartCount != 1 {
 		t.Fatalf("Expected 1 server to have been started, got %d", svc.ServiceStartCount)
 	}
+	expected := []string{"unix " + socket, "tcp "}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Fatalf("Expected to wait for %v, got %v", expected, addresses)
+	}
 }
 
 func TestClient_RPCErrors(t *testing.T) {
@@ -186,14 +540,17 @@ func TestClient_RPCErrors(t *testing.T) {
 		"rpc: service/method request ill-formed: failcommand": func() interface{} {
 			return client.StopDaemon().Error()
 		},
-		&types.MockServer{}: func() interface{} {
-			return client.StopServer(&types.MockServer{})
+		"rpc: service/method request ill-formed: failcommand (stop)": func() interface{} {
+			_, err := client.StopServer(&types.MockServer{})
+			return err.Error() + " (stop)"
 		},
-		&types.MockServer{}: func() interface{} {
-			return client.StartServer([]string{}, 0)
+		"rpc: service/method request ill-formed: failcommand (start)": func() interface{} {
+			_, err := client.StartServer([]string{}, 0)
+			return err.Error() + " (start)"
 		},
-		&types.PactListResponse{}: func() interface{} {
-			return client.ListServers()
+		"rpc: service/method request ill-formed: failcommand (list)": func() interface{} {
+			_, err := client.ListServers()
+			return err.Error() + " (list)"
 		},
 		"rpc: service/method request ill-formed: failverifycommand": func() interface{} {
 			_, err := client.VerifyProvider(types.VerifyRequest{})
@@ -292,13 +649,74 @@ func TestClient_VerifyProviderFailExecution(t *testing.T) {
 	}
 }
 
+func TestClient_VerifyProviderContextDeadline(t *testing.T) {
+	port, _ := utils.GetFreePort()
+	createDaemon(port, true)
+	waitForPortInTest(port, t)
+	defer waitForDaemonToShutdown(port, t)
+	client := &PactClient{Port: port}
+
+	ms := setupMockServer(true, t)
+	defer ms.Close()
+
+	req := types.VerifyRequest{
+		ProviderBaseURL: ms.URL,
+		PactURLs:        []string{"foo.json", "bar.json"},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := client.VerifyProviderContext(ctx, req)
+
+	if err == nil {
+		t.Fatal("Expected a error but got none")
+	}
+}
+
+func TestClient_VerifyProviderContextCancelledWaitingForProvider(t *testing.T) {
+	client := &PactClient{ /* don't supply port */ }
+	port, _ := utils.GetFreePort()
+	req := types.VerifyRequest{
+		ProviderBaseURL: fmt.Sprintf("http://localhost:%d", port),
+		PactURLs:        []string{"foo.json"},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.VerifyProviderContext(ctx, req)
+	if err == nil || !strings.Contains(err.Error(), "context canceled waiting for server to start") {
+		t.Fatalf("Expected the wait for the provider to be cancelled but got: %v", err)
+	}
+}
+
+func TestClient_ListServersContextCancelled(t *testing.T) {
+	client := &PactClient{ /* don't supply port */ }
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	list, err := client.ListServersContext(ctx)
+	if err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+
+	if len(list.Servers) != 0 {
+		t.Fatalf("Expected 0 servers, got %d", len(list.Servers))
+	}
+	if time.Since(start) > 1*time.Second {
+		t.Fatalf("Expected cancelled context to return immediately")
+	}
+}
+
 var oldTimeoutDuration = timeoutDuration
 
 func TestClient_StartServerFail(t *testing.T) {
 	timeoutDuration = 50 * time.Millisecond
 
 	client := &PactClient{ /* don't supply port */ }
-	server := client.StartServer([]string{}, 0)
+	server, err := client.StartServer([]string{}, 0)
+	if err == nil {
+		t.Fatalf("Expected error but got none")
+	}
 	if server.Port != 0 {
 		t.Fatalf("Expected server to be empty %v", server)
 	}
@@ -321,7 +739,10 @@ func TestClient_StopServer(t *testing.T) {
 func TestClient_StopServerFail(t *testing.T) {
 	timeoutDuration = 50 * time.Millisecond
 	client := &PactClient{ /* don't supply port */ }
-	res := client.StopServer(&types.MockServer{})
+	res, err := client.StopServer(&types.MockServer{})
+	if err == nil {
+		t.Fatalf("Expected error but got none")
+	}
 	should := &types.MockServer{}
 	if !reflect.DeepEqual(res, should) {
 		t.Fatalf("Expected nil object but got a difference: %v != %v", res, should)
@@ -339,7 +760,20 @@ func TestClient_StopDaemon(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Err: %v", err)
 	}
-	waitForDaemonToShutdown(port, t)
+
+	timeout := time.After(1 * time.Second)
+	for {
+		select {
+		case <-timeout:
+			t.Fatalf("Expected daemon to stop listening < 1s.")
+		case <-time.After(50 * time.Millisecond):
+			conn, err := net.Dial("tcp", fmt.Sprintf(":%d", port))
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}
 }
 
 func TestClient_StopDaemonFail(t *testing.T) {
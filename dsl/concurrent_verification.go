@@ -0,0 +1,149 @@
+package dsl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/pact-foundation/pact-go/types"
+)
+
+// pactVerification is the result of verifying a single pact.
+type pactVerification struct {
+	url      string
+	consumer string
+	response types.ProviderVerifierResponse
+	err      error
+}
+
+// verifyConcurrently verifies each pact in its own verifier process, running
+// at most request.Concurrency verifications at a time. Each worker verifies
+// against its own ProviderInstance, if they are given. The results are merged
+// into a single response, grouped by consumer.
+func (p *Pact) verifyConcurrently(ctx context.Context, request types.VerifyRequest) (types.ProviderVerifierResponse, error) {
+	log.Printf("[DEBUG] pact provider verification - verifying %d pacts with concurrency %d", len(request.PactURLs), request.Concurrency)
+
+	results := make([]pactVerification, len(request.PactURLs))
+	workers := make(chan int, request.Concurrency)
+	for worker := 0; worker < request.Concurrency; worker++ {
+		workers <- worker
+	}
+	var wg sync.WaitGroup
+
+	for i, url := range request.PactURLs {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			worker := <-workers
+			defer func() { workers <- worker }()
+
+			results[i] = verifyPact(p, ctx, instanceRequest(request, worker), url)
+		}(i, url)
+	}
+	wg.Wait()
+
+	return mergeVerifications(results)
+}
+
+// instanceRequest returns the request to verify against the ProviderInstance
+// of the worker, if instances are given.
+func instanceRequest(request types.VerifyRequest, worker int) types.VerifyRequest {
+	if worker >= len(request.ProviderInstances) {
+		return request
+	}
+	instance := request.ProviderInstances[worker]
+	request.ProviderBaseURL = instance.BaseURL
+	request.ProviderStatesSetupURL = instance.StatesSetupURL
+	return request
+}
+
+// verifyPact is replaced in tests to check the pacts verified concurrently.
+var verifyPact = (*Pact).verifyPact
+
+// verifyPact runs the verification process for a single pact.
+func (p *Pact) verifyPact(ctx context.Context, request types.VerifyRequest, url string) pactVerification {
+	request.PactURLs = []string{url}
+	result := pactVerification{
+		url:      url,
+		consumer: url,
+	}
+
+	pacts := readVerifiedPacts(ctx, request)
+	if len(pacts) > 0 {
+		result.consumer = pacts[0].Consumer.Name
+	}
+
+	result.response, result.err = p.pactClient.VerifyProviderContext(ctx, request)
+	if result.err == nil {
+		result.response.Interactions = parseInteractionResults(result.response.Examples, pacts)
+	}
+
+	return result
+}
+
+// mergeVerifications combines the results of individual pact verifications
+// into a single response, returning an error if any verification could not
+// be run.
+func mergeVerifications(results []pactVerification) (types.ProviderVerifierResponse, error) {
+	var res types.ProviderVerifierResponse
+	var errs []string
+	consumers := make(map[string]int)
+
+	for _, r := range results {
+		index, ok := consumers[r.consumer]
+		if !ok {
+			index = len(res.Consumers)
+			consumers[r.consumer] = index
+			res.Consumers = append(res.Consumers, types.ConsumerVerifierResponse{Consumer: r.consumer})
+		}
+		consumer := &res.Consumers[index]
+		consumer.PactURLs = append(consumer.PactURLs, r.url)
+
+		if r.err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", r.url, r.err))
+			consumer.Error = strings.TrimSpace(strings.Join([]string{consumer.Error, r.err.Error()}, "\n"))
+			continue
+		}
+
+		if res.Version == "" {
+			res.Version = r.response.Version
+		}
+		consumer.Examples = append(consumer.Examples, r.response.Examples...)
+		consumer.Interactions = append(consumer.Interactions, r.response.Interactions...)
+		addSummary(&consumer.Summary, r.response.Summary)
+
+		res.Examples = append(res.Examples, r.response.Examples...)
+		res.Interactions = append(res.Interactions, r.response.Interactions...)
+		addSummary(&res.Summary, r.response.Summary)
+	}
+
+	res.SummaryLine = fmt.Sprintf("%s, %s", pluralise(res.Summary.ExampleCount, "example"), pluralise(res.Summary.FailureCount, "failure"))
+
+	if len(errs) > 0 {
+		return res, errors.New(strings.Join(errs, "\n\n"))
+	}
+
+	return res, nil
+}
+
+// addSummary adds the counts of one summary to another. As verifications run
+// concurrently, the duration is the longest of the two.
+func addSummary(total *types.ProviderVerifierSummary, s types.ProviderVerifierSummary) {
+	if s.Duration > total.Duration {
+		total.Duration = s.Duration
+	}
+	total.ExampleCount += s.ExampleCount
+	total.FailureCount += s.FailureCount
+	total.PendingCount += s.PendingCount
+	total.ErrorsOutsideOfExamplesCount += s.ErrorsOutsideOfExamplesCount
+}
+
+func pluralise(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
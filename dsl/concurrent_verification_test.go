@@ -0,0 +1,146 @@
+package dsl
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pact-foundation/pact-go/types"
+	"github.com/pact-foundation/pact-go/utils"
+)
+
+func TestConcurrentVerification_VerifyProviderRaw(t *testing.T) {
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	waitForPort = func(context.Context, int, string, string, string) error {
+		return nil
+	}
+	port, _ := utils.GetFreePort()
+	createDaemon(port, true)
+	waitForPortInTest(port, t)
+
+	pact := &Pact{Port: port, LogLevel: "DEBUG", pactClient: &PactClient{Port: port}}
+	res, err := pact.VerifyProviderRaw(types.VerifyRequest{
+		ProviderBaseURL: "http://www.foo.com",
+		PactURLs:        []string{"foo.json", "bar.json", "baz.json"},
+		Concurrency:     2,
+	})
+
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+
+	if len(res.Consumers) != 3 {
+		t.Fatalf("Expected results for 3 consumers but got %d", len(res.Consumers))
+	}
+
+	if res.Consumers[0].Consumer != "foo.json" {
+		t.Fatalf("Expected results to be in order of the Pact URLs but got '%s'", res.Consumers[0].Consumer)
+	}
+}
+
+func TestConcurrentVerification_mergeVerifications(t *testing.T) {
+	example := types.ProviderVerifierExample{Status: "failed"}
+	results := []pactVerification{
+		{
+			url:      "billy-1.json",
+			consumer: "billy",
+			response: types.ProviderVerifierResponse{
+				Version:  "3.5.0",
+				Examples: []types.ProviderVerifierExample{example},
+				Summary:  types.ProviderVerifierSummary{Duration: 2, ExampleCount: 1, FailureCount: 1},
+			},
+		},
+		{
+			url:      "billy-2.json",
+			consumer: "billy",
+			response: types.ProviderVerifierResponse{
+				Summary: types.ProviderVerifierSummary{Duration: 1, ExampleCount: 2},
+			},
+		},
+		{
+			url:      "jessica.json",
+			consumer: "jessica",
+			err:      errors.New("verifier exploded"),
+		},
+	}
+
+	res, err := mergeVerifications(results)
+	if err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+
+	if res.SummaryLine != "3 examples, 1 failure" {
+		t.Fatalf("Expected summary line '3 examples, 1 failure' but got '%s'", res.SummaryLine)
+	}
+	if res.Summary.Duration != 2 {
+		t.Fatalf("Expected duration to be the longest verification but got %f", res.Summary.Duration)
+	}
+	if res.Version != "3.5.0" {
+		t.Fatalf("Expected version '3.5.0' but got '%s'", res.Version)
+	}
+	if len(res.Consumers) != 2 {
+		t.Fatalf("Expected 2 consumers but got %d", len(res.Consumers))
+	}
+	if len(res.Consumers[0].PactURLs) != 2 || res.Consumers[0].Summary.ExampleCount != 3 {
+		t.Fatalf("Expected pacts to be grouped by consumer but got %+v", res.Consumers[0])
+	}
+	if res.Consumers[1].Error != "verifier exploded" {
+		t.Fatalf("Expected consumer error but got '%s'", res.Consumers[1].Error)
+	}
+}
+
+func TestConcurrentVerification_ProviderInstances(t *testing.T) {
+	old := verifyPact
+	defer func() { verifyPact = old }()
+	var mutex sync.Mutex
+	inUse := make(map[string]bool)
+	verified := make(map[string]string)
+	verifyPact = func(p *Pact, ctx context.Context, request types.VerifyRequest, url string) pactVerification {
+		mutex.Lock()
+		if inUse[request.ProviderBaseURL] {
+			t.Errorf("Expected %s to be verified against an instance not in use", url)
+		}
+		inUse[request.ProviderBaseURL] = true
+		verified[url] = request.ProviderStatesSetupURL
+		mutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mutex.Lock()
+		inUse[request.ProviderBaseURL] = false
+		mutex.Unlock()
+		return pactVerification{url: url, consumer: url}
+	}
+
+	pact := &Pact{LogLevel: "ERROR", pactClient: &PactClient{}}
+	_, err := pact.VerifyProviderRaw(types.VerifyRequest{
+		PactURLs: []string{"foo.json", "bar.json", "baz.json", "qux.json"},
+		ProviderInstances: []types.ProviderInstance{
+			{BaseURL: "http://localhost:8001", StatesSetupURL: "http://localhost:8001/setup"},
+			{BaseURL: "http://localhost:8002", StatesSetupURL: "http://localhost:8002/setup"},
+		},
+	})
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+	if len(verified) != 4 {
+		t.Fatalf("Expected 4 pacts to be verified but got %d", len(verified))
+	}
+	for url, setup := range verified {
+		if setup != "http://localhost:8001/setup" && setup != "http://localhost:8002/setup" {
+			t.Fatalf("Expected %s to use the state setup URL of an instance but got '%s'", url, setup)
+		}
+	}
+
+	_, err = pact.VerifyProviderRaw(types.VerifyRequest{
+		PactURLs:          []string{"foo.json", "bar.json"},
+		Concurrency:       2,
+		ProviderInstances: []types.ProviderInstance{{BaseURL: "http://localhost:8001"}},
+	})
+	if err == nil {
+		t.Fatalf("Expected error for more workers than provider instances")
+	}
+}
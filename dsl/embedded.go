@@ -0,0 +1,144 @@
+package dsl
+
+import (
+	"context"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pact-foundation/pact-go/daemon"
+)
+
+// lockedDaemonTimeout is how long to wait for the Daemon recorded in a lock
+// file to respond, before assuming it is no longer running.
+var lockedDaemonTimeout = 1 * time.Second
+
+// embedded is the Daemon started within this process, shared by the Pacts
+// using it until the last of them is torn down.
+var embedded struct {
+	sync.Mutex
+	daemon *daemon.Daemon
+	port   int
+	users  int
+	done   chan struct{}
+}
+
+// useEmbeddedDaemon points the Pact at the running Daemon recorded in the
+// lock file or, if there is none, a Daemon started within this process.
+func (p *Pact) useEmbeddedDaemon() error {
+	if p.DaemonLockFile == "" {
+		p.DaemonLockFile = daemon.DefaultLockFile()
+	}
+
+	if lock, err := daemon.ReadLockFile(p.DaemonLockFile); err == nil && p.daemonRunning(lock) {
+		log.Printf("[DEBUG] pact setup - using daemon %d from lock file %s", lock.Pid, p.DaemonLockFile)
+		if isUnixNetwork(lock.Network) {
+			p.DaemonSocket = lock.Address
+		} else {
+			p.Port = lock.Port
+		}
+		return nil
+	}
+
+	port, err := startEmbeddedDaemon(p.Network, p.Host)
+	if err != nil {
+		return err
+	}
+	log.Println("[DEBUG] pact setup - using embedded daemon on port", port)
+	p.Port = port
+	p.embeddedDaemon = true
+
+	return nil
+}
+
+// daemonRunning reports whether the Daemon recorded in the lock is running,
+// and accepts requests from this Pact.
+func (p *Pact) daemonRunning(lock *daemon.Lock) bool {
+	client := &PactClient{
+		Port:      lock.Port,
+		Network:   lock.Network,
+		Address:   lock.Address,
+		Token:     p.DaemonToken,
+		TLSConfig: p.DaemonTLSConfig,
+	}
+	if isUnixNetwork(lock.Network) {
+		client.SocketPath = lock.Address
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lockedDaemonTimeout)
+	defer cancel()
+	_, err := client.ListServersContext(ctx)
+
+	return err == nil
+}
+
+// releaseEmbeddedDaemon releases the Pact's use of the embedded Daemon, if it
+// is using it.
+func (p *Pact) releaseEmbeddedDaemon() {
+	if !p.embeddedDaemon {
+		return
+	}
+	p.embeddedDaemon = false
+	p.pactClient = nil
+	p.Port = 0
+
+	stopEmbeddedDaemon()
+}
+
+// startEmbeddedDaemon starts a Daemon within this process, listening on the
+// given network and host, or shares the one already started. It returns the
+// port the Daemon is listening on.
+func startEmbeddedDaemon(network string, host string) (int, error) {
+	embedded.Lock()
+	defer embedded.Unlock()
+
+	if embedded.daemon == nil {
+		if isUnixNetwork(network) {
+			network = "tcp"
+		}
+
+		d := daemon.NewEmbeddedDaemon()
+		l, err := d.Listen(0, network, host)
+		if err != nil {
+			return 0, err
+		}
+
+		done := make(chan struct{})
+		go func() {
+			d.Serve(l)
+			close(done)
+		}()
+
+		embedded.daemon = d
+		embedded.port = l.Addr().(*net.TCPAddr).Port
+		embedded.done = done
+	}
+	embedded.users++
+
+	return embedded.port, nil
+}
+
+// stopEmbeddedDaemon stops the Daemon started within this process, once it is
+// no longer being used.
+func stopEmbeddedDaemon() {
+	embedded.Lock()
+	defer embedded.Unlock()
+
+	embedded.users--
+	if embedded.users > 0 || embedded.daemon == nil {
+		return
+	}
+
+	log.Println("[DEBUG] pact teardown - stopping embedded daemon")
+	var res string
+	embedded.daemon.StopDaemon("", &res)
+	<-embedded.done
+	embedded.daemon = nil
+}
+
+// daemonConfigured reports whether the Pact has been given a Daemon to use.
+func (p *Pact) daemonConfigured() bool {
+	return p.Port != 0 || p.DaemonSocket != "" || os.Getenv("PACT_DAEMON_SOCKET") != ""
+}
@@ -0,0 +1,116 @@
+package dsl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pact-foundation/pact-go/utils"
+)
+
+// createLockFile writes a lock file for a Daemon on the given port, in a new
+// directory.
+func createLockFile(port int, t *testing.T) string {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	path := filepath.Join(dir, "daemon.lock")
+	lock := fmt.Sprintf(`{"pid": 1, "network": "tcp", "address": "", "port": %d}`, port)
+	if err := ioutil.WriteFile(path, []byte(lock), 0600); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	return path
+}
+
+func TestPact_EmbeddedDaemon(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+	lockFile := filepath.Join(dir, "daemon.lock")
+
+	first := &Pact{EmbeddedDaemon: true, DaemonLockFile: lockFile, LogLevel: "DEBUG"}
+	if err := first.Setup(false); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	port := first.Port
+	if port == 0 || !first.embeddedDaemon {
+		t.Fatalf("Expected an embedded daemon to be started")
+	}
+	if _, err := first.pactClient.ListServers(); err != nil {
+		t.Fatalf("Expected embedded daemon to respond but got: %v", err)
+	}
+
+	second := &Pact{EmbeddedDaemon: true, DaemonLockFile: lockFile}
+	if err := second.Setup(false); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if second.Port != port {
+		t.Fatalf("Expected embedded daemon on port %d to be shared but got: %d", port, second.Port)
+	}
+
+	first.Teardown()
+	if _, err := second.pactClient.ListServers(); err != nil {
+		t.Fatalf("Expected embedded daemon to still be running but got: %v", err)
+	}
+
+	second.Teardown()
+	if second.Port != 0 || second.pactClient != nil {
+		t.Fatalf("Expected pact to be reset after teardown")
+	}
+	if conn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%d", port), 100*time.Millisecond); err == nil {
+		conn.Close()
+		t.Fatalf("Expected embedded daemon to be stopped")
+	}
+}
+
+func TestPact_EmbeddedDaemonLockFile(t *testing.T) {
+	port, _ := utils.GetFreePort()
+	createDaemon(port, true)
+	waitForPortInTest(port, t)
+	defer waitForDaemonToShutdown(port, t)
+	lockFile := createLockFile(port, t)
+	defer os.RemoveAll(filepath.Dir(lockFile))
+
+	pact := &Pact{EmbeddedDaemon: true, DaemonLockFile: lockFile}
+	if err := pact.Setup(false); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer pact.Teardown()
+
+	if pact.Port != port || pact.embeddedDaemon {
+		t.Fatalf("Expected daemon on port %d from the lock file to be used but got: %d", port, pact.Port)
+	}
+}
+
+func TestPact_EmbeddedDaemonStaleLockFile(t *testing.T) {
+	old := lockedDaemonTimeout
+	defer func() { lockedDaemonTimeout = old }()
+	lockedDaemonTimeout = 100 * time.Millisecond
+
+	port, _ := utils.GetFreePort()
+	lockFile := createLockFile(port, t)
+	defer os.RemoveAll(filepath.Dir(lockFile))
+
+	pact := &Pact{EmbeddedDaemon: true, DaemonLockFile: lockFile}
+	if err := pact.Setup(false); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer pact.Teardown()
+
+	if pact.Port == port || !pact.embeddedDaemon {
+		t.Fatalf("Expected an embedded daemon to be started in place of the stale one")
+	}
+}
+
+func TestPact_EmbeddedDaemonPortGiven(t *testing.T) {
+	port, _ := utils.GetFreePort()
+	pact := &Pact{EmbeddedDaemon: true, Port: port}
+	if err := pact.Setup(false); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if pact.Port != port || pact.embeddedDaemon {
+		t.Fatalf("Expected the given daemon port to be used")
+	}
+}
@@ -0,0 +1,77 @@
+package dsl
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/pact-foundation/pact-go/types"
+)
+
+// applyFilterEnvironment populates any unset interaction filters from the
+// PACT_CONSUMER, PACT_DESCRIPTION and PACT_PROVIDER_STATE environment variables,
+// allowing a single interaction to be re-run without changing the test.
+func applyFilterEnvironment(request *types.VerifyRequest) {
+	if len(request.FilterConsumers) == 0 {
+		if consumers := os.Getenv("PACT_CONSUMER"); consumers != "" {
+			for _, c := range strings.Split(consumers, ",") {
+				if c = strings.TrimSpace(c); c != "" {
+					request.FilterConsumers = append(request.FilterConsumers, c)
+				}
+			}
+		}
+	}
+
+	if request.FilterDescription == "" {
+		request.FilterDescription = os.Getenv("PACT_DESCRIPTION")
+	}
+
+	if request.FilterState == "" {
+		request.FilterState = os.Getenv("PACT_PROVIDER_STATE")
+	}
+}
+
+// filterPactURLs removes any Pact URLs that do not belong to one of the
+// consumers in FilterConsumers.
+func filterPactURLs(ctx context.Context, request *types.VerifyRequest) error {
+	if len(request.FilterConsumers) == 0 {
+		return nil
+	}
+	log.Println("[DEBUG] pact provider verification - filtering pacts by consumers:", request.FilterConsumers)
+
+	publisher := &Publisher{
+		request: types.PublishRequest{
+			BrokerUsername: request.BrokerUsername,
+			BrokerPassword: request.BrokerPassword,
+		},
+	}
+
+	var urls []string
+	for _, url := range request.PactURLs {
+		file, _, err := publisher.readPactFile(ctx, url)
+		if err != nil {
+			return err
+		}
+		if consumerSelected(request.FilterConsumers, file.Consumer.Name) {
+			urls = append(urls, url)
+		}
+	}
+	request.PactURLs = urls
+
+	return nil
+}
+
+// consumerSelected returns true if the consumer should be verified given
+// the set of consumer filters.
+func consumerSelected(filter []string, consumer string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, f := range filter {
+		if f == consumer {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package dsl
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/pact-foundation/pact-go/types"
+)
+
+func TestFilter_applyFilterEnvironment(t *testing.T) {
+	os.Setenv("PACT_CONSUMER", "billy, jessica")
+	os.Setenv("PACT_DESCRIPTION", "A request to login")
+	os.Setenv("PACT_PROVIDER_STATE", "User .* exists")
+	defer func() {
+		os.Unsetenv("PACT_CONSUMER")
+		os.Unsetenv("PACT_DESCRIPTION")
+		os.Unsetenv("PACT_PROVIDER_STATE")
+	}()
+
+	request := types.VerifyRequest{}
+	applyFilterEnvironment(&request)
+
+	if !reflect.DeepEqual(request.FilterConsumers, []string{"billy", "jessica"}) {
+		t.Fatalf("Expected consumers to be read from environment but got %v", request.FilterConsumers)
+	}
+	if request.FilterDescription != "A request to login" {
+		t.Fatalf("Expected description to be read from environment but got '%s'", request.FilterDescription)
+	}
+	if request.FilterState != "User .* exists" {
+		t.Fatalf("Expected state to be read from environment but got '%s'", request.FilterState)
+	}
+
+	request = types.VerifyRequest{FilterDescription: "explicit"}
+	applyFilterEnvironment(&request)
+	if request.FilterDescription != "explicit" {
+		t.Fatalf("Expected explicit description to take precedence but got '%s'", request.FilterDescription)
+	}
+}
+
+func TestFilter_filterPactURLs(t *testing.T) {
+	file := createSimplePact(true)
+	defer os.Remove(file.Name())
+
+	request := types.VerifyRequest{
+		PactURLs:        []string{file.Name()},
+		FilterConsumers: []string{"Some Consumer"},
+	}
+	if err := filterPactURLs(context.Background(), &request); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(request.PactURLs) != 1 {
+		t.Fatalf("Expected 1 PactURL but got %d", len(request.PactURLs))
+	}
+
+	request.FilterConsumers = []string{"Another Consumer"}
+	if err := filterPactURLs(context.Background(), &request); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(request.PactURLs) != 0 {
+		t.Fatalf("Expected 0 PactURLs but got %d", len(request.PactURLs))
+	}
+}
+
+func TestFilter_filterPactURLsFail(t *testing.T) {
+	request := types.VerifyRequest{
+		PactURLs:        []string{"does-not-exist.json"},
+		FilterConsumers: []string{"Some Consumer"},
+	}
+	if err := filterPactURLs(context.Background(), &request); err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+}
+
+func TestFilter_findConsumersWithFilter(t *testing.T) {
+	s := setupMockBroker(false)
+	defer s.Close()
+	request := types.VerifyRequest{
+		BrokerURL:       s.URL,
+		FilterConsumers: []string{"billy"},
+	}
+	err := findConsumers(context.Background(), "bobby", &request)
+	if err != nil {
+		t.Fatalf("Error: %s", err.Error())
+	}
+
+	if len(request.PactURLs) != 1 {
+		t.Fatalf("Expected 1 PactURL but got: %d", len(request.PactURLs))
+	}
+}
@@ -0,0 +1,305 @@
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// Match types of the matching rules written to pact files.
+const (
+	matchValue = ""
+	matchType  = "type"
+
+	// Within an EachLike, whose elements are matched by a "[*].*" rule.
+	matchArrayLike = "array"
+)
+
+// identifierPattern matches object keys that need not be quoted in the paths
+// of matching rules.
+var identifierPattern = regexp.MustCompile(`^\w+$`)
+
+// matcherOf returns the json_class of a Like, EachLike or Term in a JSON
+// value, and its fields, or "" if the value is not a matcher.
+func matcherOf(v interface{}) (string, map[string]interface{}) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return "", nil
+	}
+	class, _ := m["json_class"].(string)
+	switch class {
+	case "Pact::SomethingLike", "Pact::ArrayLike", "Pact::Term":
+		return class, m
+	}
+	return "", nil
+}
+
+// termOf returns the generated value and regular expression of a Term.
+func termOf(m map[string]interface{}) (string, string) {
+	data, _ := m["data"].(map[string]interface{})
+	generate, _ := data["generate"].(string)
+	matcher, _ := data["matcher"].(map[string]interface{})
+	regex, _ := matcher["s"].(string)
+	return generate, regex
+}
+
+// minOf returns the minimum number of elements of an EachLike.
+func minOf(m map[string]interface{}) int {
+	if min, ok := m["min"].(float64); ok && min > 1 {
+		return int(min)
+	}
+	return 1
+}
+
+// stringTerm parses a string field of a request or response, such as the
+// path or a header, returning the generated value and regular expression if
+// it is a Term.
+func stringTerm(s string) (string, string, bool) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return "", "", false
+	}
+	if class, m := matcherOf(v); class == "Pact::Term" {
+		generate, regex := termOf(m)
+		return generate, regex, true
+	}
+	return "", "", false
+}
+
+// stringMatches reports whether the value of a string field matches the
+// expected value, which may be a Term.
+func stringMatches(expected string, actual string) bool {
+	if _, regex, ok := stringTerm(expected); ok {
+		matched, err := regexp.MatchString(regex, actual)
+		return err == nil && matched
+	}
+	return expected == actual
+}
+
+// stringExample returns the value of a string field, generating it if it is
+// a Term.
+func stringExample(s string) string {
+	if generate, _, ok := stringTerm(s); ok {
+		return generate
+	}
+	return s
+}
+
+// toJSON converts a body, which may be a struct or a string of JSON, to its
+// generic JSON representation.
+func toJSON(body interface{}) interface{} {
+	if s, ok := body.(string); ok {
+		return toObject([]byte(s))
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return body
+	}
+	var v interface{}
+	if err = json.Unmarshal(data, &v); err != nil {
+		return body
+	}
+	return v
+}
+
+// reify returns the example of a JSON value, replacing its matchers by the
+// values they generate.
+func reify(v interface{}) interface{} {
+	switch class, m := matcherOf(v); class {
+	case "Pact::SomethingLike":
+		return reify(m["contents"])
+	case "Pact::ArrayLike":
+		items := make([]interface{}, minOf(m))
+		for i := range items {
+			items[i] = reify(m["contents"])
+		}
+		return items
+	case "Pact::Term":
+		generate, _ := termOf(m)
+		return generate
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			object[key] = reify(value)
+		}
+		return object
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, value := range v {
+			items[i] = reify(value)
+		}
+		return items
+	}
+	return v
+}
+
+// keyPath returns the path of a key of the object at the path, e.g.
+// "$.body.name" or "$.body['first name']".
+func keyPath(path string, key string) string {
+	if identifierPattern.MatchString(key) {
+		return path + "." + key
+	}
+	return fmt.Sprintf("%s['%s']", path, key)
+}
+
+// sortedKeys returns the keys of an object in order.
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// diffValue returns the differences of the actual JSON value from the
+// expected value, which may contain matchers, at the path. Within a Like or
+// EachLike values are compared by type. Objects may not have keys that are
+// not expected, as in requests to the Mock Server.
+func diffValue(expected interface{}, actual interface{}, path string, byType bool) []FieldMismatch {
+	switch class, m := matcherOf(expected); class {
+	case "Pact::SomethingLike":
+		return diffValue(m["contents"], actual, path, true)
+	case "Pact::ArrayLike":
+		items, ok := actual.([]interface{})
+		if !ok {
+			return []FieldMismatch{{Path: path, Expected: "an array", Actual: describeJSON(actual)}}
+		}
+		if min := minOf(m); len(items) < min {
+			return []FieldMismatch{{Path: path, Expected: fmt.Sprintf("at least %d elements", min), Actual: fmt.Sprintf("%d elements", len(items))}}
+		}
+		var diffs []FieldMismatch
+		for i, item := range items {
+			diffs = append(diffs, diffValue(m["contents"], item, fmt.Sprintf("%s[%d]", path, i), true)...)
+		}
+		return diffs
+	case "Pact::Term":
+		_, regex := termOf(m)
+		if s, ok := actual.(string); ok {
+			if matched, err := regexp.MatchString(regex, s); err == nil && matched {
+				return nil
+			}
+		}
+		return []FieldMismatch{{Path: path, Expected: fmt.Sprintf("a string matching /%s/", regex), Actual: describeJSON(actual)}}
+	}
+
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		object, ok := actual.(map[string]interface{})
+		if !ok {
+			return []FieldMismatch{{Path: path, Expected: "an object", Actual: describeJSON(actual)}}
+		}
+		var diffs []FieldMismatch
+		for _, key := range sortedKeys(e) {
+			value, ok := object[key]
+			if !ok {
+				diffs = append(diffs, FieldMismatch{Path: keyPath(path, key), Expected: describeJSON(reify(e[key]))})
+				continue
+			}
+			diffs = append(diffs, diffValue(e[key], value, keyPath(path, key), byType)...)
+		}
+		for _, key := range sortedKeys(object) {
+			if _, ok := e[key]; !ok {
+				diffs = append(diffs, FieldMismatch{Path: keyPath(path, key), Actual: describeJSON(object[key])})
+			}
+		}
+		return diffs
+	case []interface{}:
+		items, ok := actual.([]interface{})
+		if !ok {
+			return []FieldMismatch{{Path: path, Expected: "an array", Actual: describeJSON(actual)}}
+		}
+		if len(items) != len(e) {
+			return []FieldMismatch{{Path: path, Expected: fmt.Sprintf("%d elements", len(e)), Actual: fmt.Sprintf("%d elements", len(items))}}
+		}
+		var diffs []FieldMismatch
+		for i := range e {
+			diffs = append(diffs, diffValue(e[i], items[i], fmt.Sprintf("%s[%d]", path, i), byType)...)
+		}
+		return diffs
+	}
+
+	if byType {
+		if jsonType(expected) != jsonType(actual) {
+			return []FieldMismatch{{Path: path, Expected: "a " + jsonType(expected), Actual: describeJSON(actual)}}
+		}
+		return nil
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		return []FieldMismatch{{Path: path, Expected: describeJSON(expected), Actual: describeJSON(actual)}}
+	}
+	return nil
+}
+
+// jsonType returns the name of the type of a JSON value.
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+// describeJSON returns a JSON value as it appears in a body.
+func describeJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// addMatchingRules adds the matching rules of the matchers in a JSON value at
+// the path, in the format of version 2 of the Pact Specification.
+func addMatchingRules(rules map[string]interface{}, v interface{}, path string, match string) {
+	rule := func(path string) map[string]interface{} {
+		if _, ok := rules[path]; !ok {
+			rules[path] = make(map[string]interface{})
+		}
+		return rules[path].(map[string]interface{})
+	}
+
+	switch class, m := matcherOf(v); class {
+	case "Pact::SomethingLike":
+		addMatchingRules(rules, m["contents"], path, matchType)
+		return
+	case "Pact::ArrayLike":
+		rule(path)["min"] = minOf(m)
+		rule(path + "[*].*")["match"] = matchType
+		addMatchingRules(rules, m["contents"], path+"[*]", matchArrayLike)
+		return
+	case "Pact::Term":
+		_, regex := termOf(m)
+		rule(path)["match"] = "regex"
+		rule(path)["regex"] = regex
+		return
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			addMatchingRules(rules, value, keyPath(path, key), match)
+		}
+	case []interface{}:
+		for i, value := range v {
+			addMatchingRules(rules, value, fmt.Sprintf("%s[%d]", path, i), match)
+		}
+	default:
+		if match == matchType {
+			rule(path)["match"] = matchType
+		}
+	}
+}
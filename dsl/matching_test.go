@@ -0,0 +1,82 @@
+package dsl
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func Test_reify(t *testing.T) {
+	body := toJSON(fmt.Sprintf(`{"name": %s, "colours": %s, "tags": %s}`,
+		Like(`"billy"`), EachLike(Term("red", "red|green"), 2), `["a", "b"]`))
+	expected := map[string]interface{}{
+		"name":    "billy",
+		"colours": []interface{}{"red", "red"},
+		"tags":    []interface{}{"a", "b"},
+	}
+	if v := reify(body); !reflect.DeepEqual(v, expected) {
+		t.Fatalf("Expected %v but got: %v", expected, v)
+	}
+}
+
+func Test_diffValue(t *testing.T) {
+	expected := toJSON(fmt.Sprintf(`{"name": %s, "id": 1, "colours": %s}`,
+		Like(`"billy"`), EachLike(Term("red", "^(red|green)$"), 1)))
+
+	cases := []struct {
+		actual string
+		diffs  []FieldMismatch
+	}{
+		{`{"name": "sally", "id": 1, "colours": ["green", "red"]}`, nil},
+		{`{"name": 1, "id": 2, "colours": []}`, []FieldMismatch{
+			{Path: "$.body.colours", Expected: "at least 1 elements", Actual: "0 elements"},
+			{Path: "$.body.id", Expected: "1", Actual: "2"},
+			{Path: "$.body.name", Expected: "a string", Actual: "1"},
+		}},
+		{`{"name": "sally", "colours": ["blue"], "extra": true}`, []FieldMismatch{
+			{Path: "$.body.colours[0]", Expected: "a string matching /^(red|green)$/", Actual: `"blue"`},
+			{Path: "$.body.id", Expected: "1"},
+			{Path: "$.body.extra", Actual: "true"},
+		}},
+		{`[]`, []FieldMismatch{{Path: "$.body", Expected: "an object", Actual: "[]"}}},
+	}
+
+	for _, c := range cases {
+		diffs := diffValue(expected, toJSON(c.actual), "$.body", false)
+		if !reflect.DeepEqual(diffs, c.diffs) {
+			t.Fatalf("Expected diffs for %s:\n%v\nbut got:\n%v", c.actual, c.diffs, diffs)
+		}
+	}
+}
+
+func Test_addMatchingRules(t *testing.T) {
+	body := toJSON(fmt.Sprintf(`{"user": %s, "colours": %s, "first name": %s}`,
+		Like(`{"name": "billy", "id": 1}`), EachLike(Term("red", "red|green"), 2), Like(`"billy"`)))
+	rules := make(map[string]interface{})
+	addMatchingRules(rules, body, "$.body", matchValue)
+
+	expected := map[string]interface{}{
+		"$.body.user.name":     map[string]interface{}{"match": "type"},
+		"$.body.user.id":       map[string]interface{}{"match": "type"},
+		"$.body.colours":       map[string]interface{}{"min": 2},
+		"$.body.colours[*].*":  map[string]interface{}{"match": "type"},
+		"$.body.colours[*]":    map[string]interface{}{"match": "regex", "regex": "red|green"},
+		"$.body['first name']": map[string]interface{}{"match": "type"},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Fatalf("Expected matching rules %v but got: %v", expected, rules)
+	}
+}
+
+func Test_stringMatches(t *testing.T) {
+	if !stringMatches("foo", "foo") || stringMatches("foo", "bar") {
+		t.Fatalf("Expected strings to match if equal")
+	}
+	term := Term("application/json", `application\\/json.*`)
+	if !stringMatches(term, "application/json; charset=utf-8") || stringMatches(term, "text/plain") {
+		t.Fatalf("Expected strings to match the Term")
+	}
+	if example := stringExample(term); example != "application/json" {
+		t.Fatalf("Expected the generated value of the Term but got: %s", example)
+	}
+}
@@ -0,0 +1,95 @@
+package dsl
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MockServerConfig is the address of the Mock Server, and how to connect to
+// it, for the integration test of VerifyWithConfig.
+type MockServerConfig struct {
+	// BaseURL of the Mock Server, e.g. "http://localhost:1234" or
+	// "https://[::1]:1234".
+	BaseURL string
+
+	// Host of the Mock Server, without brackets, e.g. "localhost" or "::1".
+	Host string
+
+	// Port of the Mock Server.
+	Port int
+
+	// TLSConfig trusting the Mock Server, if it is served over HTTPS.
+	TLSConfig *tls.Config
+
+	// Client is an HTTP client configured to connect to the Mock Server.
+	Client *http.Client
+}
+
+// MockServerConfig returns the address of the Mock Server, and how to connect
+// to it. It is only available once the Mock Server has been started. If the
+// Transport is used, the Client uses it and there is no Port.
+func (p *Pact) MockServerConfig() MockServerConfig {
+	config := MockServerConfig{
+		BaseURL:   p.MockServerURL(),
+		Host:      strings.Trim(p.Host, "[]"),
+		TLSConfig: p.MockServerTLSConfig(),
+		Client:    &http.Client{},
+	}
+	if p.Server != nil {
+		config.Port = p.mockServerPort()
+	}
+	if p.transport != nil {
+		config.Client.Transport = p.transport
+	}
+	if config.TLSConfig != nil {
+		config.Client.Transport = &http.Transport{TLSClientConfig: config.TLSConfig}
+	}
+	return config
+}
+
+// MockServerURL returns the base URL of the Mock Server, e.g.
+// "http://localhost:1234", or "https://localhost:1234" if SSL is set. If
+// RecordRequests is set, it is the URL of the proxy recording the requests.
+// If the Transport is used it is "http://" and the Host, as requests to any
+// URL are served by the Transport.
+func (p *Pact) MockServerURL() string {
+	if p.transport != nil {
+		host := strings.Trim(p.Host, "[]")
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		return fmt.Sprintf("http://%s", host)
+	}
+	if p.Server == nil {
+		return ""
+	}
+	scheme := "http"
+	if p.SSL {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(strings.Trim(p.Host, "[]"), strconv.Itoa(p.mockServerPort())))
+}
+
+// mockServerPort is the port requests to the Mock Server are sent to, which
+// is that of the recording proxy if requests are recorded.
+func (p *Pact) mockServerPort() int {
+	if p.recorder != nil {
+		return p.recorder.port
+	}
+	return p.Server.Port
+}
+
+// VerifyWithConfig is like Verify, passing the integration test the address
+// of the Mock Server, so that it need not be built from the Pact.
+func (p *Pact) VerifyWithConfig(integrationTest func(config MockServerConfig) error) error {
+	if err := p.start(); err != nil {
+		return err
+	}
+	return p.Verify(func() error {
+		return integrationTest(p.MockServerConfig())
+	})
+}
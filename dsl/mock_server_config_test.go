@@ -0,0 +1,93 @@
+package dsl
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/pact-foundation/pact-go/types"
+)
+
+func TestPact_MockServerURL(t *testing.T) {
+	pact := &Pact{Host: "localhost"}
+	if url := pact.MockServerURL(); url != "" {
+		t.Fatalf("Expected no URL without a mock server but got: %s", url)
+	}
+
+	pact.Server = &types.MockServer{Port: 1234}
+	if url := pact.MockServerURL(); url != "http://localhost:1234" {
+		t.Fatalf("Expected http URL but got: %s", url)
+	}
+
+	pact.SSL = true
+	if url := pact.MockServerURL(); url != "https://localhost:1234" {
+		t.Fatalf("Expected https URL but got: %s", url)
+	}
+
+	for _, host := range []string{"::1", "[::1]"} {
+		pact.Host = host
+		if url := pact.MockServerURL(); url != "https://[::1]:1234" {
+			t.Fatalf("Expected IPv6 URL for host %s but got: %s", host, url)
+		}
+	}
+}
+
+func TestPact_VerifyWithConfig(t *testing.T) {
+	ms := setupMockServer(true, t)
+	defer ms.Close()
+
+	pact := &Pact{
+		Server: &types.MockServer{Port: getPort(ms.URL)},
+		Host:   "127.0.0.1",
+	}
+	pact.
+		AddInteraction().
+		UponReceiving("Some name for the test").
+		WithRequest(Request{}).
+		WillRespondWith(Response{})
+
+	var config MockServerConfig
+	err := pact.VerifyWithConfig(func(c MockServerConfig) error {
+		config = c
+		res, err := c.Client.Get(c.BaseURL)
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	expected := fmt.Sprintf("http://127.0.0.1:%d", getPort(ms.URL))
+	if config.BaseURL != expected || config.Host != "127.0.0.1" || config.Port != getPort(ms.URL) {
+		t.Fatalf("Expected mock server config for %s but got: %+v", expected, config)
+	}
+	if config.TLSConfig != nil || config.Client == nil {
+		t.Fatalf("Expected a plain HTTP client but got: %+v", config)
+	}
+}
+
+func TestPact_MockServerConfigSSL(t *testing.T) {
+	pact := &Pact{
+		Server: &types.MockServer{Port: 1234},
+		Host:   "[::1]",
+		SSL:    true,
+	}
+	if _, err := pact.setupTLS(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer pact.removeTLS()
+
+	config := pact.MockServerConfig()
+	if config.BaseURL != "https://[::1]:1234" || config.Host != "::1" || config.Port != 1234 {
+		t.Fatalf("Expected IPv6 mock server config but got: %+v", config)
+	}
+	if config.TLSConfig == nil {
+		t.Fatalf("Expected a TLS config for the mock server")
+	}
+	transport, ok := config.Client.Transport.(*http.Transport)
+	if !ok || transport.TLSClientConfig != config.TLSConfig {
+		t.Fatalf("Expected client to trust the mock server but got: %+v", config.Client)
+	}
+}
@@ -2,6 +2,8 @@ package dsl
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -30,17 +32,30 @@ type MockService struct {
 	// are split over multiple files and instantiations of a Mock Server
 	// See https://github.com/pact-foundation/pact-ruby/blob/master/documentation/configuration.md#pactfile_write_mode
 	PactFileWriteMode string
+
+	// TLSConfig is used to connect to the Mock Service, if it is served over
+	// HTTPS.
+	TLSConfig *tls.Config
 }
 
 // call sends a message to the Pact service
-func (m *MockService) call(method string, url string, content interface{}) error {
+func (m *MockService) call(ctx context.Context, method string, url string, content interface{}) error {
+	_, err := m.request(ctx, method, url, content)
+	return err
+}
+
+// request sends a message to the Pact service, returning the response body.
+func (m *MockService) request(ctx context.Context, method string, url string, content interface{}) ([]byte, error) {
 	body, err := json.Marshal(content)
 	if err != nil {
 		fmt.Println(err)
-		return err
+		return nil, err
 	}
 
 	client := &http.Client{}
+	if m.TLSConfig != nil {
+		client.Transport = &http.Transport{TLSClientConfig: m.TLSConfig}
+	}
 	var req *http.Request
 	if method == "POST" {
 		req, err = http.NewRequest(method, url, bytes.NewReader(body))
@@ -48,49 +63,100 @@ func (m *MockService) call(method string, url string, content interface{}) error
 		req, err = http.NewRequest(method, url, nil)
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	req = req.WithContext(ctx)
 	req.Header.Set("X-Pact-Mock-Service", "true")
 	req.Header.Set("Content-Type", "application/json")
 
 	res, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	responseBody, err := ioutil.ReadAll(res.Body)
 	res.Body.Close()
 	log.Printf("[DEBUG] mock service response Body: %s\n", responseBody)
 	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		return errors.New(string(responseBody))
+		return nil, errors.New(string(responseBody))
 	}
-	return err
+	return responseBody, err
 }
 
 // DeleteInteractions removes any previous Mock Service Interactions.
 func (m *MockService) DeleteInteractions() error {
+	return m.DeleteInteractionsContext(context.Background())
+}
+
+// DeleteInteractionsContext removes any previous Mock Service Interactions.
+func (m *MockService) DeleteInteractionsContext(ctx context.Context) error {
 	log.Println("[DEBUG] mock service delete interactions")
 	url := fmt.Sprintf("%s/interactions", m.BaseURL)
-	return m.call("DELETE", url, nil)
+	return m.call(ctx, "DELETE", url, nil)
 }
 
 // AddInteraction adds a new Pact Mock Service interaction.
 func (m *MockService) AddInteraction(interaction *Interaction) error {
+	return m.AddInteractionContext(context.Background(), interaction)
+}
+
+// AddInteractionContext adds a new Pact Mock Service interaction.
+func (m *MockService) AddInteractionContext(ctx context.Context, interaction *Interaction) error {
 	log.Println("[DEBUG] mock service add interaction")
 	url := fmt.Sprintf("%s/interactions", m.BaseURL)
-	return m.call("POST", url, interaction)
+	return m.call(ctx, "POST", url, interaction)
 }
 
 // Verify confirms that all interactions were called.
 func (m *MockService) Verify() error {
+	return m.VerifyContext(context.Background())
+}
+
+// VerifyContext confirms that all interactions were called. A
+// *VerificationError describes the requests that did not match.
+func (m *MockService) VerifyContext(ctx context.Context) error {
 	log.Println("[DEBUG] mock service verify")
 	url := fmt.Sprintf("%s/interactions/verification", m.BaseURL)
-	return m.call("GET", url, nil)
+	err := m.call(ctx, "GET", url, nil)
+	if err != nil {
+		if verr := parseVerificationError(err.Error()); verr != nil {
+			return verr
+		}
+	}
+	return err
+}
+
+// ReceivedRequests returns the requests received by the Mock Service since
+// its interactions were last deleted, if it is recording them (see
+// Pact.RecordRequests).
+func (m *MockService) ReceivedRequests() ([]ReceivedRequest, error) {
+	return m.ReceivedRequestsContext(context.Background())
+}
+
+// ReceivedRequestsContext returns the requests received by the Mock Service.
+func (m *MockService) ReceivedRequestsContext(ctx context.Context) ([]ReceivedRequest, error) {
+	log.Println("[DEBUG] mock service received requests")
+	url := fmt.Sprintf("%s%s", m.BaseURL, receivedRequestsPath)
+	body, err := m.request(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var requests []ReceivedRequest
+	if err = json.Unmarshal(body, &requests); err != nil {
+		return nil, fmt.Errorf("unable to read received requests: %s", err)
+	}
+	return requests, nil
 }
 
 // WritePact writes the pact file to disk.
 func (m *MockService) WritePact() error {
+	return m.WritePactContext(context.Background())
+}
+
+// WritePactContext writes the pact file to disk.
+func (m *MockService) WritePactContext(ctx context.Context) error {
 	log.Println("[DEBUG] mock service write pact")
 
 	if m.Consumer == "" || m.Provider == "" {
@@ -111,5 +177,5 @@ func (m *MockService) WritePact() error {
 	}
 
 	url := fmt.Sprintf("%s/pact", m.BaseURL)
-	return m.call("POST", url, pact)
+	return m.call(ctx, "POST", url, pact)
 }
@@ -1,6 +1,9 @@
 package dsl
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -53,6 +56,25 @@ func TestMockService_AddInteraction(t *testing.T) {
 	}
 }
 
+func TestMockService_TLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	mockService := &MockService{BaseURL: ts.URL}
+	if err := mockService.Verify(); err == nil {
+		t.Fatalf("Expected untrusted certificate error but got none")
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(serverCertificate(ts))
+	mockService.TLSConfig = &tls.Config{RootCAs: pool}
+	if err := mockService.Verify(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+}
+
 func TestMockService_AddInteractionFail(t *testing.T) {
 	ms := setupMockServer(false, t)
 	defer ms.Close()
@@ -133,6 +155,23 @@ func TestMockService_Verify(t *testing.T) {
 	}
 }
 
+func TestMockService_VerifyContextCancelled(t *testing.T) {
+	ms := setupMockServer(true, t)
+	defer ms.Close()
+
+	mockService := &MockService{
+		BaseURL: ms.URL,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := mockService.VerifyContext(ctx)
+
+	if err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+}
+
 func TestMockService_VerifyFail(t *testing.T) {
 	ms := setupMockServer(false, t)
 	defer ms.Close()
@@ -151,7 +190,7 @@ func TestMockService_VerifyFail(t *testing.T) {
 func TestMockService_callBadMethod(t *testing.T) {
 	mockService := &MockService{}
 
-	err := mockService.call("BADVERB", "%%", nil)
+	err := mockService.call(context.Background(), "BADVERB", "%%", nil)
 	if err == nil {
 		t.Fatalf("Expected error but got none")
 	}
@@ -161,7 +200,7 @@ func TestMockService_callNoBroker(t *testing.T) {
 	port, _ := utils.GetFreePort()
 	mockService := &MockService{}
 
-	err := mockService.call("GET", fmt.Sprintf("http://localhost:%d/", port), nil)
+	err := mockService.call(context.Background(), "GET", fmt.Sprintf("http://localhost:%d/", port), nil)
 
 	if err == nil {
 		t.Fatalf("Expected error but got none")
@@ -172,7 +211,7 @@ func TestMockService_callInvalidObject(t *testing.T) {
 	port, _ := utils.GetFreePort()
 	mockService := &MockService{}
 
-	err := mockService.call("GET", fmt.Sprintf("http://localhost:%d/", port), math.Inf(-1))
+	err := mockService.call(context.Background(), "GET", fmt.Sprintf("http://localhost:%d/", port), math.Inf(-1))
 
 	if err == nil {
 		t.Fatalf("Expected error but got none")
@@ -5,10 +5,14 @@ collaboration test cases, and Provider contract test verification.
 package dsl
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/logutils"
@@ -16,6 +20,9 @@ import (
 	"github.com/pact-foundation/pact-go/utils"
 )
 
+// errorLogLines is the number of lines of Mock Server output added to errors.
+const errorLogLines = 50
+
 // Pact is the container structure to run the Consumer Pact test cases.
 type Pact struct {
 	// Current server for the consumer.
@@ -72,15 +79,91 @@ type Pact struct {
 	// Defaults to 'tcp'
 	Network string
 
-	// Ports MockServer can be deployed to, can be CSV or Range with a dash
-	// Example "1234", "12324,5667", "1234-5667"
+	// Ports MockServer can be deployed to, can be CSV, Range with a dash or a
+	// mix of both. The Daemon allocates a free port from them, on the Host.
+	// Example "1234", "12324,5667", "1234-5667", "1234,5000-5010"
 	AllowedMockServerPorts string
+
+	// DaemonSocket is the unix domain socket the Daemon is listening on, if it
+	// was started on the "unix" network. Used instead of the Port.
+	// Defaults to the PACT_DAEMON_SOCKET environment variable.
+	DaemonSocket string
+
+	// DaemonToken is presented to the Daemon, if it was started with a token.
+	// Defaults to the PACT_DAEMON_TOKEN environment variable.
+	DaemonToken string
+
+	// DaemonTLSConfig is used to connect to the Daemon, if it is served over
+	// TLS.
+	DaemonTLSConfig *tls.Config
+
+	// EmbeddedDaemon, if no Port or DaemonSocket is given, uses the Daemon
+	// recorded in DaemonLockFile if it is running, or else starts a Daemon
+	// within the test process, which is stopped by the last Teardown. There is
+	// then no need to run `pact-go daemon` before the tests.
+	EmbeddedDaemon bool
+
+	// DaemonLockFile records the Daemon started by `pact-go daemon`.
+	// Defaults to daemon.DefaultLockFile().
+	DaemonLockFile string
+
+	// Used to detect if the Pact is using the embedded Daemon.
+	embeddedDaemon bool
+
+	// SSL serves the Mock Server over HTTPS, with a self-signed certificate
+	// generated for the Host unless an SSLCert and SSLKey are given. Clients
+	// of the Mock Server should use MockServerTLSConfig to trust it.
+	SSL bool
+
+	// SSLCert is the PEM encoded certificate the Mock Server presents, if SSL
+	// is set. Any other certificates in the file, such as the CA that signed
+	// it, are trusted by MockServerTLSConfig.
+	SSLCert string
+
+	// SSLKey is the PEM encoded private key of the SSLCert.
+	SSLKey string
+
+	// ColourOutput highlights the differences in a *VerificationError
+	// returned by Verify with ANSI colours.
+	ColourOutput bool
+
+	// RecordRequests records the requests received by the Mock Server, for
+	// ReceivedRequests, with a proxy in front of it. Requests must be sent to
+	// MockServerURL, rather than the Server.Port, to be recorded.
+	RecordRequests bool
+
+	// The proxy recording the requests received by the Mock Server.
+	recorder *requestRecorder
+
+	// The Transport serving the interactions in memory, if used.
+	transport *memoryTransport
+
+	// Used to detect if tests have been forked from the Pact by ForTest.
+	forked bool
+
+	// Used to detect if the Pact was forked for a test by ForTest.
+	forTest bool
+
+	// Used to detect if any interactions have been verified.
+	verified bool
+
+	// The certificates trusted by MockServerTLSConfig.
+	sslCAs *x509.CertPool
+
+	// The certificate presented by the Mock Server.
+	sslCertificate *tls.Certificate
+
+	// Directory of the generated Mock Server certificate.
+	sslDir string
 }
 
 // AddInteraction creates a new Pact interaction, initialising all
 // required things. Will automatically start a Mock Service if none running.
+// If the Mock Service cannot be started, the error is returned from Verify.
 func (p *Pact) AddInteraction() *Interaction {
-	p.Setup(true)
+	if err := p.start(); err != nil {
+		log.Println("[ERROR] pact add interaction - unable to start mock server:", err)
+	}
 	log.Printf("[DEBUG] pact add interaction")
 	i := &Interaction{}
 	p.Interactions = append(p.Interactions, i)
@@ -89,55 +172,45 @@ func (p *Pact) AddInteraction() *Interaction {
 
 // Setup starts the Pact Mock Server. This is usually called before each test
 // suite begins. AddInteraction() will automatically call this if no Mock Server
-// has been started.
-func (p *Pact) Setup(startMockServer bool) *Pact {
-	p.setupLogging()
+// has been started. An error is returned if the Mock Server could not be
+// started, for example if the Daemon is not running, no port is available or
+// the Mock Server process exited.
+func (p *Pact) Setup(startMockServer bool) error {
+	p.setDefaults()
 	log.Printf("[DEBUG] pact setup")
-	dir, _ := os.Getwd()
-
-	if p.Network == "" {
-		p.Network = "tcp"
-	}
-
-	if p.Host == "" {
-		p.Host = "localhost"
-	}
-
-	if p.LogDir == "" {
-		p.LogDir = fmt.Sprintf(filepath.Join(dir, "logs"))
-	}
 
-	if p.PactDir == "" {
-		p.PactDir = fmt.Sprintf(filepath.Join(dir, "pacts"))
-	}
-
-	if p.SpecificationVersion == 0 {
-		p.SpecificationVersion = 2
+	if p.pactClient == nil && p.EmbeddedDaemon && !p.daemonConfigured() {
+		if err := p.useEmbeddedDaemon(); err != nil {
+			return fmt.Errorf("unable to start embedded daemon: %s", err)
+		}
 	}
 
 	if p.pactClient == nil {
-		client := &PactClient{Port: p.Port, Network: p.Network, Address: p.Host}
+		client := &PactClient{
+			Port:       p.Port,
+			Network:    p.Network,
+			Address:    p.Host,
+			SocketPath: p.DaemonSocket,
+			Token:      p.DaemonToken,
+			TLSConfig:  p.DaemonTLSConfig,
+		}
 		p.pactClient = client
 	}
 
-	if p.PactFileWriteMode == "" {
-		p.PactFileWriteMode = "overwrite"
-	}
+	if p.Server == nil && startMockServer {
+		if p.AllowedMockServerPorts != "" {
+			if _, err := utils.ParsePorts(p.AllowedMockServerPorts); err != nil {
+				return fmt.Errorf("invalid AllowedMockServerPorts %s: %s", p.AllowedMockServerPorts, err)
+			}
+		}
+		log.Println("[DEBUG] starting mock service")
 
-	// Need to predefine due to scoping
-	var port int
-	var perr error
-	if p.AllowedMockServerPorts != "" {
-		port, perr = utils.FindPortInRange(p.AllowedMockServerPorts)
-	} else {
-		port, perr = utils.GetFreePort()
-	}
-	if perr != nil {
-		log.Println("[ERROR] unable to find free port, mockserver will fail to start")
-	}
-	log.Println("[DEBUG] starting mock service on port:", port)
+		sslArgs, err := p.setupTLS()
+		if err != nil {
+			p.removeTLS()
+			return fmt.Errorf("unable to configure mock server SSL: %s", err)
+		}
 
-	if p.Server == nil && startMockServer {
 		args := []string{
 			"--pact-specification-version",
 			fmt.Sprintf("%d", p.SpecificationVersion),
@@ -152,11 +225,59 @@ func (p *Pact) Setup(startMockServer bool) *Pact {
 			"--pact-file-write-mode",
 			p.PactFileWriteMode,
 		}
+		args = append(args, sslArgs...)
+
+		// The Daemon allocates the port, so that parallel tests can't race for it
+		var server *types.MockServer
+		if p.AllowedMockServerPorts != "" {
+			server, err = p.pactClient.StartServerInRange(args, p.AllowedMockServerPorts)
+		} else {
+			server, err = p.pactClient.StartServer(args, 0)
+		}
+		if err != nil {
+			p.removeTLS()
+			return fmt.Errorf("unable to start mock server: %s", err)
+		}
+		p.Server = server
 
-		p.Server = p.pactClient.StartServer(args, port)
+		if p.RecordRequests {
+			if err = p.startRecorder(); err != nil {
+				return fmt.Errorf("unable to record mock server requests: %s", err)
+			}
+		}
 	}
 
-	return p
+	return nil
+}
+
+// setDefaults configures logging, and the defaults of the Pact.
+func (p *Pact) setDefaults() {
+	p.setupLogging()
+	dir, _ := os.Getwd()
+
+	if p.Network == "" {
+		p.Network = "tcp"
+	}
+
+	if p.Host == "" {
+		p.Host = "localhost"
+	}
+
+	if p.LogDir == "" {
+		p.LogDir = fmt.Sprintf(filepath.Join(dir, "logs"))
+	}
+
+	if p.PactDir == "" {
+		p.PactDir = fmt.Sprintf(filepath.Join(dir, "pacts"))
+	}
+
+	if p.SpecificationVersion == 0 {
+		p.SpecificationVersion = 2
+	}
+
+	if p.PactFileWriteMode == "" {
+		p.PactFileWriteMode = "overwrite"
+	}
 }
 
 // Configure logging
@@ -175,67 +296,185 @@ func (p *Pact) setupLogging() {
 	log.Printf("[DEBUG] pact setup logging")
 }
 
-// Teardown stops the Pact Mock Server. This usually is called on completion
-// of each test suite.
-func (p *Pact) Teardown() *Pact {
+// Teardown stops the Pact Mock Server, and the embedded Daemon if it is no
+// longer being used. This usually is called on completion of each test suite.
+// An error is returned if the Mock Server could not be stopped cleanly.
+func (p *Pact) Teardown() error {
 	log.Printf("[DEBUG] teardown")
-	if p.Server != nil {
-		p.Server = p.pactClient.StopServer(p.Server)
+	defer p.releaseEmbeddedDaemon()
+	defer p.removeTLS()
+	p.stopRecorder()
+	if p.Server == nil {
+		return nil
+	}
+
+	server, err := p.pactClient.StopServer(p.Server)
+	if err != nil {
+		return fmt.Errorf("unable to stop mock server: %s", err)
+	}
+	p.Server = server
+	if server.Status != 0 {
+		return fmt.Errorf("mock server on port %d did not stop cleanly: %s", server.Port, server.Error)
 	}
-	return p
+
+	return nil
 }
 
 // Verify runs the current test case against a Mock Service.
-// Will cleanup interactions between tests within a suite.
+// Will cleanup interactions between tests within a suite. If the requests
+// made do not match the interactions, the error is a *VerificationError,
+// suggesting interactions for any requests that matched none. This is so
+// even if the test fails, as it usually does when the Mock Service responds
+// to such a request with an error, in which case Err is the test's error.
 func (p *Pact) Verify(integrationTest func() error) error {
-	p.Setup(true)
+	mockServer, err := p.addInteractions()
+	if err != nil {
+		return err
+	}
+
+	// Run the integration test
+	err = integrationTest()
+	if err != nil {
+		return p.testFailure(mockServer, err)
+	}
+
+	return p.verifyInteractions(mockServer)
+}
+
+// testFailure returns the error of a failed integration test, or else a
+// *VerificationError including it if the requests made so far did not match
+// the interactions, which are left in place.
+func (p *Pact) testFailure(mockServer *MockService, err error) error {
+	var verr *VerificationError
+	if p.transport != nil {
+		verr = p.transport.mismatches()
+	} else if e, ok := mockServer.Verify().(*VerificationError); ok {
+		verr = e
+	}
+	if verr == nil {
+		return p.checkServer(err)
+	}
+
+	verr.Err = err
+	p.explainVerificationError(verr)
+	return verr
+}
+
+// addInteractions sets up the interactions on the Mock Server, starting it if
+// need be, returning the client to verify them with.
+func (p *Pact) addInteractions() (*MockService, error) {
+	if err := p.start(); err != nil {
+		return nil, err
+	}
 	log.Printf("[DEBUG] pact verify")
+	if p.transport != nil {
+		p.transport.setInteractions(p.Interactions)
+		return nil, nil
+	}
 	mockServer := &MockService{
-		BaseURL:  fmt.Sprintf("http://%s:%d", p.Host, p.Server.Port),
-		Consumer: p.Consumer,
-		Provider: p.Provider,
+		BaseURL:   p.MockServerURL(),
+		Consumer:  p.Consumer,
+		Provider:  p.Provider,
+		TLSConfig: p.MockServerTLSConfig(),
 	}
 
 	for _, interaction := range p.Interactions {
 		err := mockServer.AddInteraction(interaction)
 		if err != nil {
-			return err
+			return nil, p.checkServer(err)
 		}
 	}
 
-	// Run the integration test
-	err := integrationTest()
-	if err != nil {
-		return err
-	}
+	return mockServer, nil
+}
 
-	// Run Verification Process
-	err = mockServer.Verify()
+// verifyInteractions verifies the interactions on the Mock Server, and clears
+// them once verified.
+func (p *Pact) verifyInteractions(mockServer *MockService) error {
+	if p.transport != nil {
+		return p.verifyTransport()
+	}
+	err := mockServer.Verify()
+	if verr, ok := err.(*VerificationError); ok {
+		p.explainVerificationError(verr)
+		return verr
+	}
 	if err != nil {
-		return err
+		return p.checkServer(err)
 	}
 
 	// Clear out interations
 	p.Interactions = make([]*Interaction, 0)
+	p.verified = true
 
-	return mockServer.DeleteInteractions()
+	return p.checkServer(mockServer.DeleteInteractions())
+}
+
+// explainVerificationError adds the differences of the incorrect requests,
+// if they were recorded, and suggested interactions for the requests that
+// matched none, to the error.
+func (p *Pact) explainVerificationError(verr *VerificationError) {
+	verr.Colour = p.ColourOutput
+	var received []ReceivedRequest
+	if p.recorder != nil || p.transport != nil {
+		var err error
+		if received, err = p.ReceivedRequests(); err != nil {
+			log.Println("[WARN] unable to get received requests:", err)
+		}
+	}
+	verr.addDiffs(received)
+	verr.suggestInteractions(received)
+}
+
+// checkServer adds the exit code of the Mock Server to the error if the
+// Mock Server has died, as the error itself is usually an unhelpful
+// "connection refused", along with the last lines of its output.
+func (p *Pact) checkServer(err error) error {
+	if err == nil || p.Server == nil || p.Server.Pid == 0 {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutDuration)
+	defer cancel()
+
+	server, serr := p.pactClient.ServerLogsContext(ctx, p.Server)
+	if serr != nil {
+		return err
+	}
+	if server.State == types.MockServerExited {
+		err = fmt.Errorf("%s\n\nThe Mock Server on port %d has exited with code %d", err, p.Server.Port, server.Status)
+	}
+	if lines := server.Logs; len(lines) > 0 {
+		if len(lines) > errorLogLines {
+			lines = lines[len(lines)-errorLogLines:]
+		}
+		err = fmt.Errorf("%s\n\nMock Server output (last %d lines):\n%s", err, len(lines), strings.Join(lines, "\n"))
+	}
+
+	return err
 }
 
 // WritePact should be called writes when all tests have been performed for a
 // given Consumer <-> Provider pair. It will write out the Pact to the
 // configured file.
 func (p *Pact) WritePact() error {
-	p.Setup(true)
+	if err := p.start(); err != nil {
+		return err
+	}
+	if p.transport != nil {
+		return p.writeTransportPact()
+	}
 	log.Printf("[DEBUG] pact write Pact file")
 	mockServer := MockService{
-		BaseURL:           fmt.Sprintf("http://%s:%d", p.Host, p.Server.Port),
+		BaseURL:           p.MockServerURL(),
 		Consumer:          p.Consumer,
 		Provider:          p.Provider,
 		PactFileWriteMode: p.PactFileWriteMode,
+		TLSConfig:         p.MockServerTLSConfig(),
 	}
 	err := mockServer.WritePact()
 	if err != nil {
-		return err
+		return p.checkServer(err)
 	}
 
 	return nil
@@ -244,20 +483,63 @@ func (p *Pact) WritePact() error {
 // VerifyProviderRaw reads the provided pact files and runs verification against
 // a running Provider API, providing raw response from the Verification process.
 func (p *Pact) VerifyProviderRaw(request types.VerifyRequest) (types.ProviderVerifierResponse, error) {
-	p.Setup(false)
+	return p.VerifyProviderRawContext(context.Background(), request)
+}
+
+// VerifyProviderRawContext is like VerifyProviderRaw, but stops the
+// verification (and any verifier processes) when the context is done.
+func (p *Pact) VerifyProviderRawContext(ctx context.Context, request types.VerifyRequest) (types.ProviderVerifierResponse, error) {
+	if err := p.Setup(false); err != nil {
+		return types.ProviderVerifierResponse{}, err
+	}
+	applyFilterEnvironment(&request)
+
+	// Only verify the pacts of the requested consumers
+	err := filterPactURLs(ctx, &request)
+	if err != nil {
+		return types.ProviderVerifierResponse{}, err
+	}
 
 	// If we provide a Broker, we go to it to find consumers
 	if request.BrokerURL != "" {
 		log.Printf("[DEBUG] pact provider verification - finding all consumers from broker: %s", request.BrokerURL)
-		err := findConsumers(p.Provider, &request)
+		err = findConsumers(ctx, p.Provider, &request)
 		if err != nil {
 			return types.ProviderVerifierResponse{}, err
 		}
 	}
 
+	if len(request.FilterConsumers) > 0 && len(request.PactURLs) == 0 {
+		return types.ProviderVerifierResponse{}, fmt.Errorf("no pacts found for consumers: %s", strings.Join(request.FilterConsumers, ", "))
+	}
+
 	log.Printf("[DEBUG] pact provider verification")
 
-	return p.pactClient.VerifyProvider(request)
+	if len(request.ProviderInstances) > 0 {
+		if request.Concurrency == 0 {
+			request.Concurrency = len(request.ProviderInstances)
+		}
+		if request.Concurrency > len(request.ProviderInstances) {
+			return types.ProviderVerifierResponse{}, fmt.Errorf("concurrency %d exceeds the %d provider instances", request.Concurrency, len(request.ProviderInstances))
+		}
+	}
+
+	var res types.ProviderVerifierResponse
+	if request.Concurrency > 1 && len(request.PactURLs) > 1 {
+		res, err = p.verifyConcurrently(ctx, request)
+	} else {
+		request = instanceRequest(request, 0)
+		res, err = p.pactClient.VerifyProviderContext(ctx, request)
+		if err == nil {
+			res.Interactions = parseInteractionResults(res.Examples, readVerifiedPacts(ctx, request))
+		}
+	}
+
+	if err == nil {
+		err = writeReports(request, res)
+	}
+
+	return res, err
 }
 
 // VerifyProvider accepts an instance of `*testing.T`
@@ -0,0 +1,231 @@
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// pactFile is a pact file written for the interactions verified with the
+// Transport, as the Mock Server would write it.
+type pactFile struct {
+	Consumer     pactParticipant   `json:"consumer"`
+	Provider     pactParticipant   `json:"provider"`
+	Interactions []json.RawMessage `json:"interactions"`
+	Metadata     pactMetadata      `json:"metadata"`
+}
+
+type pactParticipant struct {
+	Name string `json:"name"`
+}
+
+type pactMetadata struct {
+	PactSpecificationVersion string `json:"pactSpecificationVersion"`
+}
+
+// pactInteraction is an interaction in a pact file, with the values of its
+// matchers generated and their matching rules.
+type pactInteraction struct {
+	Description string       `json:"description"`
+	State       string       `json:"providerState,omitempty"`
+	Request     pactRequest  `json:"request"`
+	Response    pactResponse `json:"response"`
+}
+
+type pactRequest struct {
+	Method        string                 `json:"method"`
+	Path          string                 `json:"path"`
+	Query         string                 `json:"query,omitempty"`
+	Headers       map[string]string      `json:"headers,omitempty"`
+	Body          interface{}            `json:"body,omitempty"`
+	MatchingRules map[string]interface{} `json:"matchingRules,omitempty"`
+}
+
+type pactResponse struct {
+	Status        int                    `json:"status"`
+	Headers       map[string]string      `json:"headers,omitempty"`
+	Body          interface{}            `json:"body,omitempty"`
+	MatchingRules map[string]interface{} `json:"matchingRules,omitempty"`
+}
+
+// writeTransportPact writes the pact file for the interactions verified with
+// the Transport. In "merge" mode the interactions are added to those of an
+// existing pact file, as the Mock Server does: an interaction with the same
+// description and provider state as an existing one must have the same
+// request and response, or an error is returned and the file is left as it
+// is.
+func (p *Pact) writeTransportPact() error {
+	log.Printf("[DEBUG] pact write Pact file for transport")
+	file := filepath.Join(p.PactDir, pactFileName(p.Consumer, p.Provider))
+	pact := pactFile{
+		Consumer: pactParticipant{Name: p.Consumer},
+		Provider: pactParticipant{Name: p.Provider},
+		Metadata: pactMetadata{PactSpecificationVersion: fmt.Sprintf("%d.0.0", p.SpecificationVersion)},
+	}
+
+	var interactions []json.RawMessage
+	for _, i := range p.transport.verifiedInteractions() {
+		raw, err := json.Marshal(newPactInteraction(i, p.SpecificationVersion))
+		if err != nil {
+			return err
+		}
+		interactions = append(interactions, raw)
+	}
+
+	if p.PactFileWriteMode == "merge" {
+		existing, err := readPactInteractions(file)
+		if err != nil {
+			return err
+		}
+		for _, raw := range existing {
+			found, err := containsInteraction(interactions, raw)
+			if err != nil {
+				return fmt.Errorf("unable to merge into pact file %s: %s", file, err)
+			}
+			if !found {
+				pact.Interactions = append(pact.Interactions, raw)
+			}
+		}
+	}
+
+	pact.Interactions = append(pact.Interactions, interactions...)
+	if pact.Interactions == nil {
+		pact.Interactions = []json.RawMessage{}
+	}
+
+	data, err := json.MarshalIndent(pact, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(p.PactDir, 0755); err != nil {
+		return fmt.Errorf("unable to create pact directory: %s", err)
+	}
+	return ioutil.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// readPactInteractions returns the interactions of an existing pact file, if
+// any.
+func readPactInteractions(file string) ([]json.RawMessage, error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read pact file %s: %s", file, err)
+	}
+	var pact struct {
+		Interactions []json.RawMessage `json:"interactions"`
+	}
+	if err = json.Unmarshal(data, &pact); err != nil {
+		return nil, fmt.Errorf("unable to read pact file %s: %s", file, err)
+	}
+	return pact.Interactions, nil
+}
+
+// mergedInteraction is an interaction of a pact file, as compared when
+// merging.
+type mergedInteraction struct {
+	Description string      `json:"description"`
+	State       string      `json:"providerState"`
+	Request     interface{} `json:"request"`
+	Response    interface{} `json:"response"`
+}
+
+// containsInteraction reports whether an interaction with the same
+// description and provider state is in the interactions, returning an error
+// if its request or response differ.
+func containsInteraction(interactions []json.RawMessage, interaction json.RawMessage) (bool, error) {
+	var existing mergedInteraction
+	if err := json.Unmarshal(interaction, &existing); err != nil {
+		return false, err
+	}
+
+	for _, raw := range interactions {
+		var i mergedInteraction
+		if err := json.Unmarshal(raw, &i); err != nil {
+			return false, err
+		}
+		if i.Description != existing.Description || i.State != existing.State {
+			continue
+		}
+		if !reflect.DeepEqual(i.Request, existing.Request) || !reflect.DeepEqual(i.Response, existing.Response) {
+			return false, fmt.Errorf("the interaction %q with provider state %q differs from the existing one in its request or response", i.Description, i.State)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// newPactInteraction returns the interaction as it is written to a pact file
+// of the specification version. Matching rules are only written for version
+// 2 or later.
+func newPactInteraction(i Interaction, specificationVersion int) pactInteraction {
+	request := pactRequest{
+		Method: strings.ToUpper(i.Request.Method),
+		Path:   stringExample(i.Request.Path),
+		Query:  stringExample(i.Request.Query),
+	}
+	response := pactResponse{
+		Status: responseStatus(i.Response),
+	}
+	requestRules := make(map[string]interface{})
+	responseRules := make(map[string]interface{})
+
+	addStringRule(requestRules, i.Request.Path, "$.path")
+	addStringRule(requestRules, i.Request.Query, "$.query")
+	request.Headers = headerExamples(requestRules, i.Request.Headers)
+	response.Headers = headerExamples(responseRules, i.Response.Headers)
+	if i.Request.Body != nil {
+		body := toJSON(i.Request.Body)
+		request.Body = reify(body)
+		addMatchingRules(requestRules, body, "$.body", matchValue)
+	}
+	if i.Response.Body != nil {
+		body := toJSON(i.Response.Body)
+		response.Body = reify(body)
+		addMatchingRules(responseRules, body, "$.body", matchValue)
+	}
+
+	if specificationVersion >= 2 {
+		if len(requestRules) > 0 {
+			request.MatchingRules = requestRules
+		}
+		if len(responseRules) > 0 {
+			response.MatchingRules = responseRules
+		}
+	}
+
+	return pactInteraction{
+		Description: i.Description,
+		State:       i.State,
+		Request:     request,
+		Response:    response,
+	}
+}
+
+// headerExamples returns the values of the headers, adding the matching
+// rules of any that are Terms.
+func headerExamples(rules map[string]interface{}, headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	examples := make(map[string]string, len(headers))
+	for name, value := range headers {
+		examples[name] = stringExample(value)
+		addStringRule(rules, value, "$.headers."+name)
+	}
+	return examples
+}
+
+// addStringRule adds the matching rule of a string field at the path, if it
+// is a Term.
+func addStringRule(rules map[string]interface{}, s string, path string) {
+	if _, regex, ok := stringTerm(s); ok {
+		rules[path] = map[string]interface{}{"match": "regex", "regex": regex}
+	}
+}
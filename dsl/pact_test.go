@@ -1,6 +1,9 @@
 package dsl
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
@@ -166,13 +169,80 @@ func TestPact_VerifyFail(t *testing.T) {
 	}
 }
 
+func TestPact_VerifyMockServerExited(t *testing.T) {
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	waitForPort = func(context.Context, int, string, string, string) error {
+		return nil
+	}
+	port, _ := utils.GetFreePort()
+	_, svc := createDaemon(port, true)
+	waitForPortInTest(port, t)
+	defer waitForDaemonToShutdown(port, t)
+
+	pact := &Pact{Port: port, LogLevel: "DEBUG"}
+	if err := pact.Setup(true); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	svc.ServiceExitCodes = map[int]int{pact.Server.Pid: 1}
+
+	err := pact.Verify(func() error { return nil })
+	if err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+	if !strings.Contains(err.Error(), "has exited with code 1") {
+		t.Fatalf("Expected exit code in error but got '%s'", err.Error())
+	}
+}
+
+func TestPact_VerifyMockServerLogs(t *testing.T) {
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	waitForPort = func(context.Context, int, string, string, string) error {
+		return nil
+	}
+	port, _ := utils.GetFreePort()
+	_, svc := createDaemon(port, true)
+	waitForPortInTest(port, t)
+	defer waitForDaemonToShutdown(port, t)
+
+	pact := &Pact{Port: port, LogLevel: "DEBUG"}
+	if err := pact.Setup(true); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	logs := make([]string, errorLogLines+10)
+	for i := range logs {
+		logs[i] = fmt.Sprintf("line %d", i)
+	}
+	svc.ServiceLogs = map[int][]string{pact.Server.Pid: logs}
+
+	err := pact.Verify(func() error { return errors.New("test failed") })
+	if err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+	if !strings.Contains(err.Error(), "Mock Server output (last 50 lines):\nline 10\n") {
+		t.Fatalf("Expected mock server output in error but got '%s'", err.Error())
+	}
+	if strings.Contains(err.Error(), "line 9\n") {
+		t.Fatalf("Expected only the last lines of output in error but got '%s'", err.Error())
+	}
+}
+
 func TestPact_Setup(t *testing.T) {
 	t.Log("testing pact setup")
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	waitForPort = func(context.Context, int, string, string, string) error {
+		return nil
+	}
 	port, _ := utils.GetFreePort()
 	createDaemon(port, true)
+	waitForPortInTest(port, t)
 
 	pact := &Pact{Port: port, LogLevel: "DEBUG"}
-	pact.Setup(true)
+	if err := pact.Setup(true); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
 	if pact.Server == nil {
 		t.Fatalf("Expected server to be created")
 	}
@@ -187,8 +257,14 @@ func TestPact_Setup(t *testing.T) {
 }
 
 func TestPact_SetupWithMockServerPort(t *testing.T) {
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	waitForPort = func(context.Context, int, string, string, string) error {
+		return nil
+	}
 	port, _ := utils.GetFreePort()
 	createDaemon(port, true)
+	waitForPortInTest(port, t)
 
 	pact := &Pact{Port: port, LogLevel: "DEBUG", AllowedMockServerPorts: "32768"}
 	pact.Setup(true)
@@ -201,8 +277,14 @@ func TestPact_SetupWithMockServerPort(t *testing.T) {
 }
 
 func TestPact_SetupWithMockServerPortCSV(t *testing.T) {
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	waitForPort = func(context.Context, int, string, string, string) error {
+		return nil
+	}
 	port, _ := utils.GetFreePort()
 	createDaemon(port, true)
+	waitForPortInTest(port, t)
 
 	pact := &Pact{Port: port, LogLevel: "DEBUG", AllowedMockServerPorts: "32768,32769"}
 	pact.Setup(true)
@@ -215,8 +297,14 @@ func TestPact_SetupWithMockServerPortCSV(t *testing.T) {
 }
 
 func TestPact_SetupWithMockServerPortRange(t *testing.T) {
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	waitForPort = func(context.Context, int, string, string, string) error {
+		return nil
+	}
 	port, _ := utils.GetFreePort()
 	createDaemon(port, true)
+	waitForPortInTest(port, t)
 
 	pact := &Pact{Port: port, LogLevel: "DEBUG", AllowedMockServerPorts: "32768-32770"}
 	pact.Setup(true)
@@ -233,19 +321,19 @@ func TestPact_Invalidrange(t *testing.T) {
 	createDaemon(port, true)
 
 	pact := &Pact{Port: port, LogLevel: "DEBUG", AllowedMockServerPorts: "abc-32770"}
-	pact.Setup(true)
-	if pact.Server == nil {
-		t.Fatalf("Expected server to be created")
+	err := pact.Setup(true)
+	if err == nil {
+		t.Fatalf("Expected error but got none")
 	}
-	if pact.Server.Port != 0 {
-		t.Fatalf("Expected mock daemon to be started on specific port")
+	if pact.Server != nil {
+		t.Fatalf("Expected server not to be created")
 	}
 }
 
 func TestPact_Teardown(t *testing.T) {
 	old := waitForPort
 	defer func() { waitForPort = old }()
-	waitForPort = func(int, string, string, string) error {
+	waitForPort = func(context.Context, int, string, string, string) error {
 		return nil
 	}
 	port, _ := utils.GetFreePort()
@@ -254,7 +342,9 @@ func TestPact_Teardown(t *testing.T) {
 
 	pact := &Pact{Port: port, LogLevel: "DEBUG"}
 	pact.Setup(true)
-	pact.Teardown()
+	if err := pact.Teardown(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
 	if pact.Server.Status != 0 {
 		t.Fatalf("Expected server exit status to be 0 but got %d", pact.Server.Status)
 	}
@@ -263,7 +353,7 @@ func TestPact_Teardown(t *testing.T) {
 func TestPact_VerifyProvider(t *testing.T) {
 	old := waitForPort
 	defer func() { waitForPort = old }()
-	waitForPort = func(int, string, string, string) error {
+	waitForPort = func(context.Context, int, string, string, string) error {
 		return nil
 	}
 	port, _ := utils.GetFreePort()
@@ -286,7 +376,7 @@ func TestPact_VerifyProviderBroker(t *testing.T) {
 	defer s.Close()
 	old := waitForPort
 	defer func() { waitForPort = old }()
-	waitForPort = func(int, string, string, string) error {
+	waitForPort = func(context.Context, int, string, string, string) error {
 		return nil
 	}
 	port, _ := utils.GetFreePort()
@@ -311,7 +401,7 @@ func TestPact_VerifyProviderBrokerNoConsumers(t *testing.T) {
 	defer s.Close()
 	old := waitForPort
 	defer func() { waitForPort = old }()
-	waitForPort = func(int, string, string, string) error {
+	waitForPort = func(context.Context, int, string, string, string) error {
 		return nil
 	}
 	port, _ := utils.GetFreePort()
@@ -332,7 +422,7 @@ func TestPact_VerifyProviderBrokerNoConsumers(t *testing.T) {
 func TestPact_VerifyProviderFail(t *testing.T) {
 	old := waitForPort
 	defer func() { waitForPort = old }()
-	waitForPort = func(int, string, string, string) error {
+	waitForPort = func(context.Context, int, string, string, string) error {
 		return nil
 	}
 	port, _ := utils.GetFreePort()
@@ -0,0 +1,127 @@
+package dsl
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"unicode"
+)
+
+// forkMutex guards the Pacts copied by ForTest and WithProvider.
+var forkMutex sync.Mutex
+
+// ForTest returns a copy of the Pact for a single test, with its own Mock
+// Server and interactions, so that tests may run in parallel. The interactions
+// of every test are merged into the one pact file, which is written once all
+// of the tests of the package have run by RunTests, which then stops the Mock
+// Servers, so it must be used in TestMain, or the test fails, e.g.:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(dsl.RunTests(m))
+//	}
+//
+//	func TestLogin(t *testing.T) {
+//		t.Parallel()
+//		pact := pact.ForTest(t)
+//		pact.AddInteraction()...
+//		pact.VerifyT(t, ...)
+//	}
+//
+// Any pact file left by a previous run is removed when the first test is
+// forked, unless the PactFileWriteMode of the Pact is "merge".
+func (p *Pact) ForTest(t *testing.T) *Pact {
+	if !runningTests {
+		t.Fatal("ForTest requires dsl.RunTests in TestMain")
+	}
+	test, err := p.fork()
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+	registerVerifiedPact(test)
+
+	// Mock Servers for parallel tests start in parallel
+	if err = test.Setup(true); err != nil {
+		t.Fatal("Error:", err)
+	}
+	log.Println("[DEBUG] pact started mock server for test:", t.Name())
+
+	return test
+}
+
+// fork copies the configuration of the Pact, and its connection to the
+// Daemon, for a test. The pact file of a previous run is removed by the first
+// fork.
+func (p *Pact) fork() (*Pact, error) {
+	if p.transport != nil {
+		return nil, errors.New("ForTest can't be used with the Transport, as it starts a Mock Server")
+	}
+
+	forkMutex.Lock()
+	defer forkMutex.Unlock()
+
+	if err := p.Setup(false); err != nil {
+		return nil, err
+	}
+	if !p.forked {
+		p.forked = true
+		if p.PactFileWriteMode != "merge" {
+			err := os.Remove(filepath.Join(p.PactDir, pactFileName(p.Consumer, p.Provider)))
+			if err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("unable to remove previous pact file: %s", err)
+			}
+		}
+	}
+
+	test := p.copyConfig()
+	test.PactFileWriteMode = "merge"
+	test.forTest = true
+	return test, nil
+}
+
+// copyConfig copies the configuration of the Pact, and its connection to the
+// Daemon, for a new Mock Server.
+func (p *Pact) copyConfig() *Pact {
+	return &Pact{
+		Port:                   p.Port,
+		pactClient:             p.pactClient,
+		Consumer:               p.Consumer,
+		Provider:               p.Provider,
+		LogLevel:               p.LogLevel,
+		logFilter:              p.logFilter,
+		LogDir:                 p.LogDir,
+		PactDir:                p.PactDir,
+		PactFileWriteMode:      p.PactFileWriteMode,
+		SpecificationVersion:   p.SpecificationVersion,
+		Host:                   p.Host,
+		Network:                p.Network,
+		AllowedMockServerPorts: p.AllowedMockServerPorts,
+		DaemonSocket:           p.DaemonSocket,
+		DaemonToken:            p.DaemonToken,
+		DaemonTLSConfig:        p.DaemonTLSConfig,
+		DaemonLockFile:         p.DaemonLockFile,
+		SSL:                    p.SSL,
+		SSLCert:                p.SSLCert,
+		SSLKey:                 p.SSLKey,
+		ColourOutput:           p.ColourOutput,
+		RecordRequests:         p.RecordRequests,
+	}
+}
+
+// pactFileName is the name of the pact file the Mock Server writes for the
+// Consumer and Provider.
+func pactFileName(consumer string, provider string) string {
+	filenamify := func(name string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return '_'
+			}
+			return r
+		}, strings.ToLower(name))
+	}
+	return fmt.Sprintf("%s-%s.json", filenamify(consumer), filenamify(provider))
+}
@@ -0,0 +1,76 @@
+package dsl
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/pact-foundation/pact-go/utils"
+)
+
+func TestPact_ForTest(t *testing.T) {
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	waitForPort = func(context.Context, int, string, string, string) error {
+		return nil
+	}
+	port, _ := utils.GetFreePort()
+	_, svc := createDaemon(port, true)
+	waitForPortInTest(port, t)
+	defer waitForDaemonToShutdown(port, t)
+
+	dir, _ := ioutil.TempDir("", "pact-go-test")
+	defer os.RemoveAll(dir)
+	pactFile := filepath.Join(dir, "my_consumer-my_provider.json")
+	ioutil.WriteFile(pactFile, []byte("{}"), 0600)
+
+	pact := &Pact{Port: port, Consumer: "My Consumer", Provider: "My Provider", PactDir: dir}
+	var mutex sync.Mutex
+	tests := make(map[int]*Pact)
+
+	t.Run("group", func(t *testing.T) {
+		for _, name := range []string{"one", "two"} {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				test := pact.ForTest(t)
+				test.AddInteraction().UponReceiving(t.Name())
+
+				if test.Server == nil || test.PactFileWriteMode != "merge" || len(test.Interactions) != 1 {
+					t.Fatalf("Expected a mock server and interactions for the test but got: %+v", test)
+				}
+				mutex.Lock()
+				tests[test.Server.Port] = test
+				mutex.Unlock()
+			})
+		}
+	})
+
+	if len(tests) != 2 {
+		t.Fatalf("Expected 2 tests to have their own mock server but got: %d", len(tests))
+	}
+	if pact.Server != nil || len(pact.Interactions) != 0 {
+		t.Fatalf("Expected the pact to not be used by the tests but got: %+v", pact)
+	}
+	if _, err := os.Stat(pactFile); !os.IsNotExist(err) {
+		t.Fatalf("Expected previous pact file to be removed but got: %v", err)
+	}
+	if svc.ServiceStopCount != 0 {
+		t.Fatalf("Expected the mock servers to be left running for RunTests but got: %d stopped", svc.ServiceStopCount)
+	}
+
+	if err := teardownVerifiedPacts(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if svc.ServiceStopCount != 2 {
+		t.Fatalf("Expected the mock servers to be stopped by RunTests but got: %d", svc.ServiceStopCount)
+	}
+}
+
+func Test_pactFileName(t *testing.T) {
+	if name := pactFileName("My Consumer", "my\tProvider"); name != "my_consumer-my_provider.json" {
+		t.Fatalf("Expected pact file name my_consumer-my_provider.json but got: %s", name)
+	}
+}
@@ -0,0 +1,124 @@
+package dsl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// WithProvider returns a Pact with the same configuration, and connection to
+// the Daemon, for the Consumer's interactions with another Provider. Each has
+// its own Mock Server, and may be verified together as Providers. An error is
+// returned if the Pact could not be set up to connect to the Daemon, or if it
+// uses the Transport, as each Provider needs a Mock Server.
+func (p *Pact) WithProvider(provider string) (*Pact, error) {
+	if p.transport != nil {
+		return nil, errors.New("WithProvider can't be used with the Transport, as it starts a Mock Server")
+	}
+
+	forkMutex.Lock()
+	defer forkMutex.Unlock()
+
+	if err := p.Setup(false); err != nil {
+		return nil, fmt.Errorf("unable to setup pact with provider %s: %s", provider, err)
+	}
+	pact := p.copyConfig()
+	pact.Provider = provider
+	return pact, nil
+}
+
+// Providers are the Pacts of a Consumer with each of the Providers it calls,
+// e.g. from WithProvider, so that a test of the Consumer calling them all can
+// be verified against each of them, writing a pact per Provider.
+type Providers []*Pact
+
+// ProvidersError is returned by Providers.Verify when the interactions with
+// any of the Providers were not verified, with the error for each Provider.
+// The error for a Provider is a *VerificationError if the requests made did
+// not match its interactions.
+type ProvidersError map[string]error
+
+// Error returns the errors of each Provider, in order of the Provider names.
+func (e ProvidersError) Error() string {
+	providers := make([]string, 0, len(e))
+	for provider := range e {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	var b bytes.Buffer
+	for i, provider := range providers {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "Provider %s: %s", provider, e[provider])
+	}
+	return b.String()
+}
+
+// Verify sets up the interactions on the Mock Server of every Provider, runs
+// the integration test once, and then verifies the interactions of every
+// Provider, clearing those that were verified. If the interactions could not
+// be set up or the integration test fails, its error is returned and the
+// interactions of every Provider are cleared, so that none are left on the
+// Mock Servers for the next test. Otherwise any failed verifications are
+// returned as a ProvidersError.
+func (ps Providers) Verify(integrationTest func() error) error {
+	mockServers := make([]*MockService, len(ps))
+	for i, p := range ps {
+		mockServer, err := p.addInteractions()
+		if err != nil {
+			ps.clearInteractions()
+			return fmt.Errorf("unable to add interactions for provider %s: %s", p.Provider, err)
+		}
+		mockServers[i] = mockServer
+	}
+
+	// Run the integration test
+	if err := integrationTest(); err != nil {
+		ps.clearInteractions()
+		return err
+	}
+
+	errs := make(ProvidersError)
+	for i, p := range ps {
+		if err := p.verifyInteractions(mockServers[i]); err != nil {
+			errs[p.Provider] = err
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// clearInteractions removes the interactions of every Provider, and from
+// their Mock Servers.
+func (ps Providers) clearInteractions() {
+	for _, p := range ps {
+		p.clearInteractions()
+	}
+}
+
+// WritePact writes the pact file of every Provider, returning the first
+// error.
+func (ps Providers) WritePact() error {
+	for _, p := range ps {
+		if err := p.WritePact(); err != nil {
+			return fmt.Errorf("unable to write pact for provider %s: %s", p.Provider, err)
+		}
+	}
+	return nil
+}
+
+// Teardown stops the Mock Server of every Provider, returning the first error.
+func (ps Providers) Teardown() error {
+	var first error
+	for _, p := range ps {
+		if err := p.Teardown(); err != nil && first == nil {
+			first = fmt.Errorf("unable to teardown provider %s: %s", p.Provider, err)
+		}
+	}
+	return first
+}
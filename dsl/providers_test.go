@@ -0,0 +1,165 @@
+package dsl
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pact-foundation/pact-go/types"
+)
+
+// setupProviderServer creates a fake Mock Server, counting the requests to
+// each of its paths, that fails verification if given a failure message.
+func setupProviderServer(failure string) (*httptest.Server, map[string]int, *sync.Mutex) {
+	var mutex sync.Mutex
+	requests := make(map[string]int)
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests[r.Method+" "+r.URL.Path]++
+		mutex.Unlock()
+		if failure != "" && r.URL.Path == "/interactions/verification" {
+			http.Error(w, failure, http.StatusInternalServerError)
+		}
+	}))
+	return ms, requests, &mutex
+}
+
+func TestPact_WithProvider(t *testing.T) {
+	pact := &Pact{Consumer: "My Consumer", Provider: "My Provider", Port: 1234, SSL: true}
+	users, err := pact.WithProvider("Users")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if users.Provider != "Users" || users.Consumer != "My Consumer" || users.Port != 1234 || !users.SSL {
+		t.Fatalf("Expected the configuration of the pact for provider Users but got: %+v", users)
+	}
+	if users.pactClient != pact.pactClient || users.Server != nil {
+		t.Fatalf("Expected the daemon client to be shared, without the mock server")
+	}
+}
+
+func TestProviders_Verify(t *testing.T) {
+	users, usersRequests, usersMutex := setupProviderServer("")
+	defer users.Close()
+	orders, ordersRequests, ordersMutex := setupProviderServer("Missing requests:\n\tGET /orders\n")
+	defer orders.Close()
+
+	providers := Providers{
+		{Server: &types.MockServer{Port: getPort(users.URL)}, Host: "127.0.0.1", Consumer: "Consumer", Provider: "Users"},
+		{Server: &types.MockServer{Port: getPort(orders.URL)}, Host: "127.0.0.1", Consumer: "Consumer", Provider: "Orders"},
+	}
+	for _, p := range providers {
+		p.AddInteraction().UponReceiving("A request to " + p.Provider)
+	}
+
+	calls := 0
+	err := providers.Verify(func() error {
+		calls++
+		return nil
+	})
+	if calls != 1 {
+		t.Fatalf("Expected the integration test to be run once but got: %d", calls)
+	}
+
+	errs, ok := err.(ProvidersError)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("Expected a ProvidersError for 1 provider but got: %v", err)
+	}
+	verr, ok := errs["Orders"].(*VerificationError)
+	if !ok || len(verr.Missing) != 1 {
+		t.Fatalf("Expected a verification error for Orders but got: %v", errs["Orders"])
+	}
+	if !strings.HasPrefix(err.Error(), "Provider Orders: ") {
+		t.Fatalf("Expected error to name the provider but got: %s", err)
+	}
+
+	usersMutex.Lock()
+	if usersRequests["POST /interactions"] != 1 || usersRequests["DELETE /interactions"] != 1 {
+		t.Fatalf("Expected Users interactions to be added and cleared but got: %v", usersRequests)
+	}
+	usersMutex.Unlock()
+	ordersMutex.Lock()
+	if ordersRequests["POST /interactions"] != 1 || ordersRequests["DELETE /interactions"] != 0 {
+		t.Fatalf("Expected Orders interactions to be added and kept but got: %v", ordersRequests)
+	}
+	ordersMutex.Unlock()
+
+	if err = providers.WritePact(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	for _, requests := range []map[string]int{usersRequests, ordersRequests} {
+		if requests["POST /pact"] != 1 {
+			t.Fatalf("Expected a pact to be written for each provider but got: %v", requests)
+		}
+	}
+}
+
+func TestPact_WithProviderSetupFail(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go")
+	defer os.RemoveAll(dir)
+
+	pact := &Pact{Consumer: "My Consumer", Provider: "My Provider", EmbeddedDaemon: true, DaemonLockFile: filepath.Join(dir, "pact-go.lock"), Host: "not a host"}
+	if _, err := pact.WithProvider("Users"); err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+}
+
+func TestProviders_VerifyIntegrationTestFail(t *testing.T) {
+	users, usersRequests, usersMutex := setupProviderServer("")
+	defer users.Close()
+	orders, ordersRequests, ordersMutex := setupProviderServer("")
+	defer orders.Close()
+
+	providers := Providers{
+		{Server: &types.MockServer{Port: getPort(users.URL)}, Host: "127.0.0.1", Provider: "Users"},
+		{Server: &types.MockServer{Port: getPort(orders.URL)}, Host: "127.0.0.1", Provider: "Orders"},
+	}
+	for _, p := range providers {
+		p.AddInteraction().UponReceiving("A request to " + p.Provider)
+	}
+	err := providers.Verify(func() error {
+		return errors.New("integration test failed")
+	})
+	if err == nil || err.Error() != "integration test failed" {
+		t.Fatalf("Expected integration test error but got: %v", err)
+	}
+
+	for i, requests := range []map[string]int{usersRequests, ordersRequests} {
+		mutex := []*sync.Mutex{usersMutex, ordersMutex}[i]
+		mutex.Lock()
+		if requests["POST /interactions"] != 1 || requests["DELETE /interactions"] != 1 {
+			t.Fatalf("Expected the interactions of every provider to be cleared but got: %v", requests)
+		}
+		mutex.Unlock()
+	}
+	for _, p := range providers {
+		if len(p.Interactions) != 0 {
+			t.Fatalf("Expected the interactions of %s to be cleared but got: %v", p.Provider, p.Interactions)
+		}
+	}
+}
+
+func TestProvidersError_Error(t *testing.T) {
+	err := ProvidersError{
+		"Users":  errors.New("users failed"),
+		"Orders": errors.New("orders failed"),
+	}
+	expected := "Provider Orders: orders failed\n\nProvider Users: users failed"
+	if err.Error() != expected {
+		t.Fatalf("Expected error '%s' but got '%s'", expected, err.Error())
+	}
+}
+
+func TestProviders_Teardown(t *testing.T) {
+	providers := Providers{{Provider: "Users"}, {Provider: "Orders"}}
+	if err := providers.Teardown(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package dsl
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -70,7 +71,7 @@ func (p *Publisher) validate() error {
 }
 
 // call sends a message to the Pact Broker.
-func (p *Publisher) call(method string, url string, content []byte) error {
+func (p *Publisher) call(ctx context.Context, method string, url string, content []byte) error {
 	client := &http.Client{}
 	var req *http.Request
 	var err error
@@ -79,6 +80,7 @@ func (p *Publisher) call(method string, url string, content []byte) error {
 		return err
 	}
 
+	req = req.WithContext(ctx)
 	req.Header.Set("Content-Type", "application/json")
 
 	if p.request.BrokerUsername != "" && p.request.BrokerPassword != "" {
@@ -100,10 +102,10 @@ func (p *Publisher) call(method string, url string, content []byte) error {
 }
 
 // readPactFile reads Pact files from local or remote sources.
-func (p *Publisher) readPactFile(url string) (*PactFile, []byte, error) {
+func (p *Publisher) readPactFile(ctx context.Context, url string) (*PactFile, []byte, error) {
 	log.Println("[DEBUG] pact publisher: readPactFile", url)
 	if strings.HasPrefix(url, "http") {
-		return p.readRemotePactFile(url)
+		return p.readRemotePactFile(ctx, url)
 	}
 	return p.readLocalPactFile(url)
 }
@@ -137,9 +139,19 @@ func (p *Publisher) unmarshal(content []byte) (*PactFile, error) {
 }
 
 // readRemotePactFile reads a remote Pact file from an http(s) server.
-func (p *Publisher) readRemotePactFile(file string) (*PactFile, []byte, error) {
+func (p *Publisher) readRemotePactFile(ctx context.Context, file string) (*PactFile, []byte, error) {
 	log.Println("[DEBUG] pact publisher: read remote pact file", file)
-	res, err := http.Get(file)
+	req, err := http.NewRequest("GET", file, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req = req.WithContext(ctx)
+
+	if p.request.BrokerUsername != "" && p.request.BrokerPassword != "" {
+		req.SetBasicAuth(p.request.BrokerUsername, p.request.BrokerPassword)
+	}
+
+	res, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -155,35 +167,41 @@ func (p *Publisher) readRemotePactFile(file string) (*PactFile, []byte, error) {
 
 // Publish sends the Pacts to a broker, optionally tagging them
 func (p *Publisher) Publish(request types.PublishRequest) error {
+	return p.PublishContext(context.Background(), request)
+}
+
+// PublishContext sends the Pacts to a broker, optionally tagging them,
+// stopping if the context is done before publishing completes.
+func (p *Publisher) PublishContext(ctx context.Context, request types.PublishRequest) error {
 	log.Println("[DEBUG] pact publisher: publish pact")
 	p.request = request
 
 	for _, url := range request.PactURLs {
-		file, data, err := p.readPactFile(url)
+		file, data, err := p.readPactFile(ctx, url)
 		if err != nil {
 			return err
 		}
 
 		endpoint := fmt.Sprintf("%s/pacts/provider/%s/consumer/%s/version/%s", request.PactBroker, file.Provider.Name, file.Consumer.Name, request.ConsumerVersion)
 		log.Println("[DEBUG] pact publisher: putting Pact on endpoint:", endpoint)
-		err = p.call("PUT", endpoint, data)
+		err = p.call(ctx, "PUT", endpoint, data)
 		if err != nil {
 			return err
 		}
 
-		p.tagRequest(file.Consumer.Name, request)
+		p.tagRequest(ctx, file.Consumer.Name, request)
 	}
 
 	return nil
 }
 
 // tag one or more Pact files
-func (p *Publisher) tagRequest(consumerName string, request types.PublishRequest) error {
+func (p *Publisher) tagRequest(ctx context.Context, consumerName string, request types.PublishRequest) error {
 	log.Println("[DEBUG] pact publisher: tagging pacts...")
 	for _, tag := range request.Tags {
 		endpoint := fmt.Sprintf("%s/pacticipants/%s/versions/%s/tags/%s", request.PactBroker, consumerName, request.ConsumerVersion, tag)
 		log.Println("[DEBUG] pact publisher: tagging Pact:", endpoint)
-		err := p.call("PUT", endpoint, []byte{})
+		err := p.call(ctx, "PUT", endpoint, []byte{})
 		if err != nil {
 			return err
 		}
@@ -1,6 +1,7 @@
 package dsl
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io/ioutil"
@@ -252,7 +253,7 @@ func TestPublish_readRemotePactFile(t *testing.T) {
 	s, url := createMockRemoteServer(true)
 	defer s.Close()
 
-	f, _, err := p.readRemotePactFile(url)
+	f, _, err := p.readRemotePactFile(context.Background(), url)
 
 	if err != nil {
 		t.Fatalf("Err: %v", err)
@@ -272,13 +273,13 @@ func TestPublish_readRemotePactFileFail(t *testing.T) {
 	s, url := createMockRemoteServer(false)
 	defer s.Close()
 
-	_, _, err := p.readRemotePactFile(url)
+	_, _, err := p.readRemotePactFile(context.Background(), url)
 
 	if err == nil {
 		t.Fatalf("Expected error but got none")
 	}
 
-	_, _, err = p.readRemotePactFile(fmt.Sprintf("%s/iknowthisfiledoesntexist", url))
+	_, _, err = p.readRemotePactFile(context.Background(), fmt.Sprintf("%s/iknowthisfiledoesntexist", url))
 	if err == nil {
 		t.Fatalf("Expected error but got none")
 	}
@@ -289,7 +290,7 @@ func TestPublish_readPactFile(t *testing.T) {
 	s, url := createMockRemoteServer(true)
 	defer s.Close()
 
-	f, _, err := p.readPactFile(url)
+	f, _, err := p.readPactFile(context.Background(), url)
 
 	if err != nil {
 		t.Fatalf("Err: %v", err)
@@ -304,7 +305,7 @@ func TestPublish_readPactFile(t *testing.T) {
 	}
 
 	localFile := createSimplePact(true)
-	f, _, err = p.readPactFile(localFile.Name())
+	f, _, err = p.readPactFile(context.Background(), localFile.Name())
 
 	if err != nil {
 		t.Fatalf("Err: %v", err)
@@ -324,13 +325,13 @@ func TestPublish_readPactFileFail(t *testing.T) {
 	s, url := createMockRemoteServer(false)
 	defer s.Close()
 
-	_, _, err := p.readPactFile(url)
+	_, _, err := p.readPactFile(context.Background(), url)
 
 	if err == nil {
 		t.Fatalf("Expected error but got none")
 	}
 
-	_, _, err = p.readPactFile(fmt.Sprintf("%s/iknowthisfiledoesntexist", url))
+	_, _, err = p.readPactFile(context.Background(), fmt.Sprintf("%s/iknowthisfiledoesntexist", url))
 	if err == nil {
 		t.Fatalf("Expected error but got none")
 	}
@@ -354,6 +355,26 @@ func TestPublish_Publish(t *testing.T) {
 	}
 }
 
+func TestPublish_PublishContextCancelled(t *testing.T) {
+	p := &Publisher{}
+
+	f := createSimplePact(true)
+	broker := setupMockServer(true, t)
+	defer broker.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := p.PublishContext(ctx, types.PublishRequest{
+		PactURLs:        []string{f.Name()},
+		PactBroker:      broker.URL,
+		ConsumerVersion: "1.0.0",
+	})
+
+	if err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+}
+
 func TestPublish_PublishFail(t *testing.T) {
 	p := &Publisher{}
 
@@ -401,7 +422,7 @@ func TestPublish_PublishFail(t *testing.T) {
 
 func TestPublish_callFail(t *testing.T) {
 	p := &Publisher{}
-	err := p.call("GET", "%%%", nil)
+	err := p.call(context.Background(), "GET", "%%%", nil)
 
 	if err == nil {
 		t.Fatalf("Expected error but got none")
@@ -411,7 +432,7 @@ func TestPublish_callFail(t *testing.T) {
 func TestPublish_callFailNoBroker(t *testing.T) {
 	p := &Publisher{}
 	port, _ := utils.GetFreePort()
-	err := p.call("GET", fmt.Sprintf("http://localhost:%d/", port), nil)
+	err := p.call(context.Background(), "GET", fmt.Sprintf("http://localhost:%d/", port), nil)
 	if err == nil {
 		t.Fatalf("Expected error but got none")
 	}
@@ -483,7 +504,7 @@ func TestPublish_tagRequest(t *testing.T) {
 	broker := setupMockServer(true, t)
 	defer broker.Close()
 
-	err := p.tagRequest("Some Consumer", types.PublishRequest{
+	err := p.tagRequest(context.Background(), "Some Consumer", types.PublishRequest{
 		PactURLs:        []string{f.Name()},
 		PactBroker:      broker.URL,
 		ConsumerVersion: "1.0.0",
@@ -502,7 +523,7 @@ func TestPublish_tagRequestFail(t *testing.T) {
 	broker := setupMockServer(false, t)
 	defer broker.Close()
 
-	err := p.tagRequest("Some Consumer", types.PublishRequest{
+	err := p.tagRequest(context.Background(), "Some Consumer", types.PublishRequest{
 		PactURLs:        []string{f.Name()},
 		PactBroker:      broker.URL,
 		ConsumerVersion: "1.0.0",
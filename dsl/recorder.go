@@ -0,0 +1,269 @@
+package dsl
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// receivedRequestsPath is the path the requests received by the Mock Server
+// are served on, if they are recorded.
+const receivedRequestsPath = "/interactions/received"
+
+// ReceivedRequest is a request received by the Mock Server.
+type ReceivedRequest struct {
+	Method  string      `json:"method"`
+	Path    string      `json:"path"`
+	Query   string      `json:"query,omitempty"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+
+	// Status of the response from the Mock Server.
+	Status int `json:"status"`
+
+	// Interaction is the description of the interaction the request matched,
+	// if any.
+	Interaction string `json:"interaction,omitempty"`
+
+	// Mismatch is the reason given by the Mock Server for the request not
+	// matching an interaction, if it did not.
+	Mismatch string `json:"mismatch,omitempty"`
+
+	// Diffs are the fields of the request that did not match the closest
+	// interaction, if it matched none.
+	Diffs []FieldMismatch `json:"diffs,omitempty"`
+}
+
+// requestRecorder is a proxy to the Mock Server that records the requests it
+// receives, served on receivedRequestsPath. The record is cleared along with
+// the interactions of the Mock Server.
+type requestRecorder struct {
+	mutex        sync.Mutex
+	interactions []Interaction
+	requests     []ReceivedRequest
+	proxy        *httputil.ReverseProxy
+	listener     net.Listener
+	port         int
+}
+
+// startRequestRecorder starts a requestRecorder on a free TCP port of the
+// host, proxying to the Mock Server at the given URL. If a certificate is
+// given it is served over TLS, and the Mock Server is trusted with the TLS
+// config.
+func startRequestRecorder(host string, target *url.URL, cert *tls.Certificate, config *tls.Config) (*requestRecorder, error) {
+	l, err := net.Listen("tcp", net.JoinHostPort(strings.Trim(host, "[]"), "0"))
+	if err != nil {
+		return nil, err
+	}
+	addr, ok := l.Addr().(*net.TCPAddr)
+	if !ok {
+		l.Close()
+		return nil, fmt.Errorf("unable to record requests on %s", l.Addr())
+	}
+	if cert != nil {
+		l = tls.NewListener(l, &tls.Config{Certificates: []tls.Certificate{*cert}})
+	}
+
+	r := &requestRecorder{
+		proxy:    httputil.NewSingleHostReverseProxy(target),
+		listener: l,
+		port:     addr.Port,
+	}
+	if config != nil {
+		r.proxy.Transport = &http.Transport{TLSClientConfig: config}
+	}
+	log.Println("[DEBUG] recording mock server requests on port:", r.port)
+
+	go http.Serve(l, r)
+	return r, nil
+}
+
+// Close stops the requestRecorder.
+func (r *requestRecorder) Close() error {
+	return r.listener.Close()
+}
+
+// ServeHTTP proxies the request to the Mock Server, recording it unless it is
+// to the Mock Server's own API.
+func (r *requestRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	admin := req.Header.Get("X-Pact-Mock-Service") != ""
+	if admin && req.Method == "GET" && req.URL.Path == receivedRequestsPath {
+		r.mutex.Lock()
+		requests := append([]ReceivedRequest{}, r.requests...)
+		r.mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(requests)
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if admin {
+		r.proxy.ServeHTTP(w, req)
+		r.admin(req, body)
+		return
+	}
+
+	res := &recordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+	r.proxy.ServeHTTP(res, req)
+
+	received := ReceivedRequest{
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Query:   req.URL.RawQuery,
+		Headers: req.Header,
+		Body:    string(body),
+		Status:  res.status,
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	received.Mismatch = mismatchMessage(res.status, res.body.Bytes())
+	if received.Mismatch != "" {
+		received.Diffs = parseInteractionDiffs(res.body.Bytes())
+	} else {
+		received.Interaction = r.matchInteraction(received)
+	}
+	r.requests = append(r.requests, received)
+}
+
+// admin records the interactions added to, and cleared from, the Mock Server.
+func (r *requestRecorder) admin(req *http.Request, body []byte) {
+	if req.URL.Path != "/interactions" {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	switch req.Method {
+	case "POST":
+		var i Interaction
+		if err := json.Unmarshal(body, &i); err != nil {
+			log.Println("[WARN] unable to record interaction:", err)
+			return
+		}
+		r.interactions = append(r.interactions, i)
+	case "DELETE":
+		r.interactions = nil
+		r.requests = nil
+	}
+}
+
+// matchInteraction returns the description of the interaction the request
+// matched, by its method, path, query, headers and body as the Mock Server
+// matches them, or "" if it matched none or is ambiguous.
+func (r *requestRecorder) matchInteraction(received ReceivedRequest) string {
+	description := ""
+	for _, i := range r.interactions {
+		if !strings.EqualFold(i.Request.Method, received.Method) || !pathMatches(i.Request.Path, received.Path) {
+			continue
+		}
+		if fields, _ := diffRequest(i.Request, received); len(fields) > 0 {
+			continue
+		}
+		if description != "" {
+			return ""
+		}
+		description = i.Description
+	}
+	return description
+}
+
+// pathMatches reports whether the path of an interaction, which may be a
+// Term, matches the path of a request.
+func pathMatches(expected string, path string) bool {
+	return stringMatches(expected, path)
+}
+
+// mismatchMessage returns the message of a Mock Server response to a request
+// that matched no interaction, or matched several, or "" if it is not one.
+func mismatchMessage(status int, body []byte) string {
+	if status != http.StatusInternalServerError {
+		return ""
+	}
+	var res struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return ""
+	}
+	if strings.HasPrefix(res.Message, "No interaction found") || strings.HasPrefix(res.Message, "Multiple interaction") {
+		return res.Message
+	}
+	return ""
+}
+
+// recordingResponseWriter records the status and body of a response.
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status of the response.
+func (w *recordingResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body of the response.
+func (w *recordingResponseWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// startRecorder starts recording the requests received by the Mock Server.
+func (p *Pact) startRecorder() error {
+	target, err := url.Parse(p.MockServerURL())
+	if err != nil {
+		return err
+	}
+	recorder, err := startRequestRecorder(p.Host, target, p.sslCertificate, p.MockServerTLSConfig())
+	if err != nil {
+		return err
+	}
+	p.recorder = recorder
+	return nil
+}
+
+// stopRecorder stops recording the requests received by the Mock Server.
+func (p *Pact) stopRecorder() {
+	if p.recorder != nil {
+		p.recorder.Close()
+		p.recorder = nil
+	}
+}
+
+// ReceivedRequests returns the requests received by the Mock Server during
+// the current test, i.e. since the interactions were last verified. It
+// requires RecordRequests to be set.
+func (p *Pact) ReceivedRequests() ([]ReceivedRequest, error) {
+	if p.transport != nil {
+		return p.transport.receivedRequests(), nil
+	}
+	if p.recorder == nil {
+		return nil, errors.New("requests are only recorded if RecordRequests is set")
+	}
+	mockServer := &MockService{
+		BaseURL:   p.MockServerURL(),
+		TLSConfig: p.MockServerTLSConfig(),
+	}
+	return mockServer.ReceivedRequests()
+}
@@ -0,0 +1,264 @@
+package dsl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pact-foundation/pact-go/types"
+	"github.com/pact-foundation/pact-go/utils"
+)
+
+// setupRecordingPact creates a Pact recording the requests to a fake Mock
+// Server, which only has an interaction for /foo, and fails verification with
+// the given message if any.
+func setupRecordingPact(verification string, t *testing.T) (*Pact, func()) {
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if verification != "" && r.URL.Path == "/interactions/verification" {
+			http.Error(w, verification, http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("X-Pact-Mock-Service") != "" || r.URL.Path == "/foo" {
+			fmt.Fprintln(w, "Hello, client")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		diffs := `[]`
+		if r.URL.Path == "/foo/" {
+			diffs = `[{"description": "A request for foo", "path": {"EXPECTED": "/foo", "ACTUAL": "/foo/"}}]`
+		}
+		fmt.Fprintf(w, `{"message": "No interaction found for %s %s", "interaction_diffs": %s}`, r.Method, r.URL.Path, diffs)
+	}))
+
+	pact := &Pact{
+		Server:         &types.MockServer{Port: getPort(ms.URL)},
+		Host:           "127.0.0.1",
+		Network:        "tcp",
+		RecordRequests: true,
+	}
+	if err := pact.startRecorder(); err != nil {
+		ms.Close()
+		t.Fatalf("Error: %v", err)
+	}
+
+	return pact, func() {
+		pact.stopRecorder()
+		ms.Close()
+	}
+}
+
+func TestPact_ReceivedRequests(t *testing.T) {
+	pact, cleanup := setupRecordingPact("", t)
+	defer cleanup()
+
+	pact.
+		AddInteraction().
+		UponReceiving("A request for foo").
+		WithRequest(Request{Method: "POST", Path: "/foo"}).
+		WillRespondWith(Response{Status: 200})
+
+	var received []ReceivedRequest
+	err := pact.Verify(func() error {
+		res, err := http.Post(pact.MockServerURL()+"/foo?bar=baz", "application/json", strings.NewReader(`{"name": "billy"}`))
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		res, err = http.Get(pact.MockServerURL() + "/unknown")
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+
+		received, err = pact.ReceivedRequests()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 requests to be received but got: %v", received)
+	}
+	foo := received[0]
+	if foo.Method != "POST" || foo.Path != "/foo" || foo.Query != "bar=baz" || foo.Body != `{"name": "billy"}` {
+		t.Fatalf("Expected request for /foo to be recorded but got: %v", foo)
+	}
+	if foo.Headers.Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected request headers to be recorded but got: %v", foo.Headers)
+	}
+	if foo.Status != 200 || foo.Interaction != "A request for foo" || foo.Mismatch != "" {
+		t.Fatalf("Expected request to match interaction but got: %v", foo)
+	}
+	unknown := received[1]
+	if unknown.Status != 500 || unknown.Interaction != "" || unknown.Mismatch != "No interaction found for GET /unknown" {
+		t.Fatalf("Expected request to not match an interaction but got: %v", unknown)
+	}
+
+	// Verifying the interactions clears them, and the received requests
+	received, err = pact.ReceivedRequests()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(received) != 0 {
+		t.Fatalf("Expected received requests to be cleared but got: %v", received)
+	}
+}
+
+func TestPact_ReceivedRequestsDiffs(t *testing.T) {
+	pact, cleanup := setupRecordingPact("", t)
+	defer cleanup()
+
+	var received []ReceivedRequest
+	pact.Verify(func() error {
+		res, err := http.Get(pact.MockServerURL() + "/foo/")
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+
+		received, err = pact.ReceivedRequests()
+		return err
+	})
+
+	expected := []FieldMismatch{{Path: "$.path", Expected: `"/foo"`, Actual: `"/foo/"`}}
+	if len(received) != 1 || !reflect.DeepEqual(received[0].Diffs, expected) {
+		t.Fatalf("Expected the differences from the interaction to be recorded but got: %+v", received)
+	}
+}
+
+func TestPact_ReceivedRequestsUnixNetwork(t *testing.T) {
+	pact, cleanup := setupRecordingPact("", t)
+	defer cleanup()
+	pact.stopRecorder()
+
+	// The Daemon may be on a unix socket, but the Mock Server is always TCP
+	pact.Network = "unix"
+	if err := pact.startRecorder(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if pact.recorder.port == 0 {
+		t.Fatalf("Expected requests to be recorded on a TCP port")
+	}
+}
+
+func TestRequestRecorder_matchInteraction(t *testing.T) {
+	r := &requestRecorder{}
+	for _, i := range []*Interaction{
+		(&Interaction{}).UponReceiving("baz").WithRequest(Request{Method: "GET", Path: "/foo", Query: "bar=baz"}),
+		(&Interaction{}).UponReceiving("qux").WithRequest(Request{Method: "GET", Path: "/foo", Query: "bar=qux"}),
+		(&Interaction{}).UponReceiving("billy").WithRequest(Request{Method: "POST", Path: "/users", Body: `{"name": "billy"}`}),
+		(&Interaction{}).UponReceiving("sally").WithRequest(Request{Method: "POST", Path: "/users", Body: `{"name": "sally"}`}),
+		(&Interaction{}).UponReceiving("json").WithRequest(Request{Method: "GET", Path: "/orders", Headers: map[string]string{"Accept": "application/json"}}),
+		(&Interaction{}).UponReceiving("any").WithRequest(Request{Method: "GET", Path: "/orders"}),
+	} {
+		data, _ := json.Marshal(i)
+		req := httptest.NewRequest("POST", "/interactions", nil)
+		r.admin(req, data)
+	}
+
+	cases := []struct {
+		request     ReceivedRequest
+		interaction string
+	}{
+		{ReceivedRequest{Method: "GET", Path: "/foo", Query: "bar=qux"}, "qux"},
+		{ReceivedRequest{Method: "POST", Path: "/users", Body: `{"name": "sally"}`}, "sally"},
+		{ReceivedRequest{Method: "GET", Path: "/orders"}, "any"},
+		// Matches both the interaction with the header and the one without
+		{ReceivedRequest{Method: "GET", Path: "/orders", Headers: http.Header{"Accept": {"application/json"}}}, ""},
+		{ReceivedRequest{Method: "GET", Path: "/foo", Query: "bar=quux"}, ""},
+	}
+	for _, c := range cases {
+		if i := r.matchInteraction(c.request); i != c.interaction {
+			t.Fatalf("Expected %+v to match interaction %q but got: %q", c.request, c.interaction, i)
+		}
+	}
+}
+
+func TestPact_ReceivedRequestsNotRecording(t *testing.T) {
+	pact := &Pact{}
+	if _, err := pact.ReceivedRequests(); err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+}
+
+func TestMockService_ReceivedRequestsFail(t *testing.T) {
+	ms := setupMockServer(false, t)
+	defer ms.Close()
+
+	mockService := &MockService{BaseURL: ms.URL}
+	if _, err := mockService.ReceivedRequests(); err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+}
+
+func Test_pathMatches(t *testing.T) {
+	cases := []struct {
+		expected string
+		path     string
+		matches  bool
+	}{
+		{"/foo", "/foo", true},
+		{"/foo", "/bar", false},
+		{Term("/foo/1", `\\/foo\\/\\d+`), "/foo/123", true},
+		{Term("/foo/1", `\\/foo\\/\\d+`), "/foo/bar", false},
+	}
+
+	for _, c := range cases {
+		if matches := pathMatches(c.expected, c.path); matches != c.matches {
+			t.Fatalf("Expected %s matching %s to be %v", c.expected, c.path, c.matches)
+		}
+	}
+}
+
+func TestPact_ReceivedRequestsSSL(t *testing.T) {
+	ms := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ms.Close()
+
+	certPEM, keyPEM, err := utils.GenerateCertificate([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	cert, _ := tls.X509KeyPair(certPEM, keyPEM)
+	pool := x509.NewCertPool()
+	pool.AddCert(serverCertificate(ms))
+	pool.AppendCertsFromPEM(certPEM)
+
+	pact := &Pact{
+		Server:         &types.MockServer{Port: getPort(ms.URL)},
+		Host:           "127.0.0.1",
+		Network:        "tcp",
+		SSL:            true,
+		RecordRequests: true,
+		sslCAs:         pool,
+		sslCertificate: &cert,
+	}
+	if err = pact.startRecorder(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer pact.stopRecorder()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: pact.MockServerTLSConfig()}}
+	res, err := client.Get(pact.MockServerURL() + "/foo")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	res.Body.Close()
+
+	received, err := pact.ReceivedRequests()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(received) != 1 || received[0].Path != "/foo" {
+		t.Fatalf("Expected request for /foo to be recorded but got: %v", received)
+	}
+}
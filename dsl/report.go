@@ -0,0 +1,198 @@
+package dsl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pact-foundation/pact-go/types"
+)
+
+// junitTestSuites is the root element of a JUnit XML report.
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Time       string           `xml:"time,attr"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite groups the test cases of a single consumer.
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is the result of a single interaction.
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+// junitFailure describes why an interaction failed.
+type junitFailure struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// WriteJUnitReport writes the verification results as a JUnit XML report,
+// with a test suite per consumer and a test case per interaction.
+func WriteJUnitReport(w io.Writer, res types.ProviderVerifierResponse) error {
+	report := junitTestSuites{
+		Time: fmt.Sprintf("%.3f", res.Summary.Duration),
+	}
+	suites := make(map[string]int)
+
+	for _, i := range reportInteractions(res) {
+		name := reportConsumerName(i)
+		index, ok := suites[name]
+		if !ok {
+			index = len(report.TestSuites)
+			suites[name] = index
+			report.TestSuites = append(report.TestSuites, junitTestSuite{Name: name})
+		}
+		suite := &report.TestSuites[index]
+
+		testCase := junitTestCase{
+			ClassName: name,
+			Name:      reportInteractionName(i),
+		}
+		if !i.Passed {
+			testCase.Failure = &junitFailure{
+				Message:  fmt.Sprintf("%d mismatch(es)", len(i.Mismatches)),
+				Type:     reportFailureType(i),
+				Contents: reportMismatches(i, ""),
+			}
+			suite.Failures++
+			report.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+		suite.Tests++
+		report.Tests++
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteTAPReport writes the verification results in the Test Anything
+// Protocol (version 13) format, with a test per interaction.
+func WriteTAPReport(w io.Writer, res types.ProviderVerifierResponse) error {
+	interactions := reportInteractions(res)
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "TAP version 13\n1..%d\n", len(interactions))
+	for n, i := range interactions {
+		name := fmt.Sprintf("%s: %s", reportConsumerName(i), reportInteractionName(i))
+		if i.Passed {
+			fmt.Fprintf(&out, "ok %d - %s\n", n+1, name)
+			continue
+		}
+		fmt.Fprintf(&out, "not ok %d - %s\n", n+1, name)
+		fmt.Fprintf(&out, "  ---\n  message: |\n%s  ...\n", reportMismatches(i, "    "))
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// writeReports writes any reports requested in the VerifyRequest.
+func writeReports(request types.VerifyRequest, res types.ProviderVerifierResponse) error {
+	if request.JUnitReportFile != "" {
+		if err := writeReportFile(request.JUnitReportFile, res, WriteJUnitReport); err != nil {
+			return err
+		}
+	}
+
+	if request.TAPReportFile != "" {
+		if err := writeReportFile(request.TAPReportFile, res, WriteTAPReport); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeReportFile creates the given report file (and its directory) and
+// writes the results to it.
+func writeReportFile(file string, res types.ProviderVerifierResponse, writer func(io.Writer, types.ProviderVerifierResponse) error) error {
+	log.Println("[DEBUG] pact provider verification - writing report:", file)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	err = writer(f, res)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// reportInteractions returns the interaction results to report on, deriving
+// them from the raw examples if required.
+func reportInteractions(res types.ProviderVerifierResponse) []types.InteractionResult {
+	if len(res.Interactions) > 0 {
+		return res.Interactions
+	}
+	return parseInteractionResults(res.Examples, nil)
+}
+
+func reportConsumerName(i types.InteractionResult) string {
+	if i.Consumer == "" {
+		return "pact"
+	}
+	return i.Consumer
+}
+
+func reportInteractionName(i types.InteractionResult) string {
+	if i.ProviderState == "" {
+		return i.Description
+	}
+	return fmt.Sprintf("Given %s %s", i.ProviderState, i.Description)
+}
+
+// reportFailureType is the type of the first mismatch of a failed interaction.
+func reportFailureType(i types.InteractionResult) string {
+	if len(i.Mismatches) == 0 {
+		return types.MismatchTypeError
+	}
+	return i.Mismatches[0].Type
+}
+
+// reportMismatches formats the mismatches of an interaction, one per line,
+// with each line prefixed by indent.
+func reportMismatches(i types.InteractionResult, indent string) string {
+	var out bytes.Buffer
+	for _, m := range i.Mismatches {
+		line := fmt.Sprintf("%s: %s", m.Type, m.Message)
+		if m.Path != "" {
+			line = fmt.Sprintf("%s (%s): %s", m.Type, m.Path, m.Message)
+		}
+		for _, l := range strings.Split(line, "\n") {
+			fmt.Fprintf(&out, "%s%s\n", indent, l)
+		}
+	}
+	return out.String()
+}
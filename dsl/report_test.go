@@ -0,0 +1,94 @@
+package dsl
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pact-foundation/pact-go/types"
+)
+
+var reportResponse = types.ProviderVerifierResponse{
+	Interactions: []types.InteractionResult{
+		{
+			Consumer:      "billy",
+			Description:   "A request to login",
+			ProviderState: "User billy exists",
+			Passed:        true,
+		},
+		{
+			Consumer:    "jessica",
+			Description: "A request to logout",
+			Mismatches: []types.Mismatch{
+				{Type: types.MismatchTypeBody, Path: "$.lastName", Message: `Expected "bar" but got "baz"`},
+			},
+		},
+	},
+}
+
+func TestReport_WriteJUnitReport(t *testing.T) {
+	var out bytes.Buffer
+	err := WriteJUnitReport(&out, reportResponse)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	report := out.String()
+	for _, expected := range []string{
+		`<testsuites tests="2" failures="1"`,
+		`<testsuite name="billy" tests="1" failures="0">`,
+		`<testcase classname="billy" name="Given User billy exists A request to login"></testcase>`,
+		`<failure message="1 mismatch(es)" type="body">body ($.lastName): Expected &#34;bar&#34; but got &#34;baz&#34;`,
+	} {
+		if !strings.Contains(report, expected) {
+			t.Fatalf("Expected report to contain '%s' but got:\n%s", expected, report)
+		}
+	}
+}
+
+func TestReport_WriteTAPReport(t *testing.T) {
+	var out bytes.Buffer
+	err := WriteTAPReport(&out, reportResponse)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	expected := `TAP version 13
+1..2
+ok 1 - billy: Given User billy exists A request to login
+not ok 2 - jessica: A request to logout
+  ---
+  message: |
+    body ($.lastName): Expected "bar" but got "baz"
+  ...
+`
+	if out.String() != expected {
+		t.Fatalf("Expected report:\n%s\nbut got:\n%s", expected, out.String())
+	}
+}
+
+func TestReport_writeReports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pactgo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	request := types.VerifyRequest{
+		JUnitReportFile: filepath.Join(dir, "reports", "junit.xml"),
+		TAPReportFile:   filepath.Join(dir, "reports", "pact.tap"),
+	}
+	err = writeReports(request, reportResponse)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	for _, file := range []string{request.JUnitReportFile, request.TAPReportFile} {
+		if _, err := os.Stat(file); err != nil {
+			t.Fatalf("Expected report '%s' to be written: %v", file, err)
+		}
+	}
+}
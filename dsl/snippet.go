@@ -0,0 +1,93 @@
+package dsl
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// snippetHeaders are the request headers included in suggested interactions.
+// Others, such as User-Agent, are usually incidental to the request.
+var snippetHeaders = []string{"Accept", "Content-Type"}
+
+// suggestInteractions adds a suggested interaction to the error for each of
+// the received requests that matched no interaction, or for each unexpected
+// request if the requests were not recorded.
+func (e *VerificationError) suggestInteractions(received []ReceivedRequest) {
+	var unmatched []ReceivedRequest
+	if received != nil {
+		for _, r := range received {
+			if r.Mismatch != "" {
+				unmatched = append(unmatched, r)
+			}
+		}
+	} else {
+		for _, r := range e.Unexpected {
+			path := strings.SplitN(r.Path, "?", 2)
+			request := ReceivedRequest{Method: r.Method, Path: path[0]}
+			if len(path) > 1 {
+				request.Query = path[1]
+			}
+			unmatched = append(unmatched, request)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, r := range unmatched {
+		snippet := interactionSnippet(r)
+		if !seen[snippet] {
+			seen[snippet] = true
+			e.Suggestions = append(e.Suggestions, snippet)
+		}
+	}
+}
+
+// interactionSnippet returns Go code adding an interaction for the request.
+func interactionSnippet(r ReceivedRequest) string {
+	var b bytes.Buffer
+	b.WriteString("pact.\n\tAddInteraction().\n")
+	fmt.Fprintf(&b, "\tUponReceiving(%s).\n", strconv.Quote(fmt.Sprintf("A %s request to %s", r.Method, r.Path)))
+	b.WriteString("\tWithRequest(dsl.Request{\n")
+	fmt.Fprintf(&b, "\t\tMethod: %s,\n", strconv.Quote(r.Method))
+	fmt.Fprintf(&b, "\t\tPath:   %s,\n", strconv.Quote(r.Path))
+	if r.Query != "" {
+		fmt.Fprintf(&b, "\t\tQuery:  %s,\n", strconv.Quote(r.Query))
+	}
+
+	var headers []string
+	for _, name := range snippetHeaders {
+		if value := r.Headers.Get(name); value != "" {
+			headers = append(headers, fmt.Sprintf("\t\t\t%s: %s,\n", strconv.Quote(name), strconv.Quote(value)))
+		}
+	}
+	if len(headers) > 0 {
+		b.WriteString("\t\tHeaders: map[string]string{\n")
+		b.WriteString(strings.Join(headers, ""))
+		b.WriteString("\t\t},\n")
+	}
+
+	if r.Body != "" {
+		// gofmt only aligns the Body with the fields before it if there are no
+		// headers between them
+		align := "  "
+		if len(headers) > 0 {
+			align = ""
+		}
+		fmt.Fprintf(&b, "\t\tBody: %s%s,\n", align, quoteBody(r.Body))
+	}
+	b.WriteString("\t}).\n")
+	b.WriteString("\tWillRespondWith(dsl.Response{\n\t\tStatus: 200,\n\t})")
+
+	return b.String()
+}
+
+// quoteBody quotes the body as a raw string literal if possible, as it is
+// usually JSON.
+func quoteBody(body string) string {
+	if strings.ContainsAny(body, "`\r") || !utf8.ValidString(body) {
+		return strconv.Quote(body)
+	}
+	return "`" + body + "`"
+}
@@ -0,0 +1,146 @@
+package dsl
+
+import (
+	"errors"
+	"fmt"
+	"go/format"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestInteractionSnippet(t *testing.T) {
+	cases := []ReceivedRequest{
+		{Method: "GET", Path: "/foo"},
+		{Method: "GET", Path: "/foo", Query: "bar=baz", Body: "name=billy"},
+		{
+			Method:  "POST",
+			Path:    "/foo",
+			Headers: http.Header{"Content-Type": {"application/json"}, "User-Agent": {"Go-http-client/1.1"}},
+			Body:    "{\n  \"name\": \"billy\"\n}",
+		},
+		{Method: "POST", Path: "/foo", Body: "`quoted`\r\n"},
+	}
+
+	for _, c := range cases {
+		snippet := interactionSnippet(c)
+		src := "package main\n\nfunc main() {\n\t" + strings.Replace(snippet, "\n", "\n\t", -1) + "\n}\n"
+		formatted, err := format.Source([]byte(src))
+		if err != nil {
+			t.Fatalf("Expected snippet to be valid Go but got: %v\n%s", err, src)
+		}
+		if string(formatted) != src {
+			t.Fatalf("Expected snippet to be formatted but got:\n%s\nexpected:\n%s", src, formatted)
+		}
+		if strings.Contains(snippet, "User-Agent") {
+			t.Fatalf("Expected incidental headers to be omitted but got:\n%s", snippet)
+		}
+	}
+}
+
+func TestInteractionSnippet_Content(t *testing.T) {
+	snippet := interactionSnippet(ReceivedRequest{
+		Method:  "POST",
+		Path:    "/foo",
+		Query:   "bar=baz",
+		Headers: http.Header{"Content-Type": {"application/json"}},
+		Body:    `{"name": "billy"}`,
+	})
+	expected := "pact.\n" +
+		"\tAddInteraction().\n" +
+		"\tUponReceiving(\"A POST request to /foo\").\n" +
+		"\tWithRequest(dsl.Request{\n" +
+		"\t\tMethod: \"POST\",\n" +
+		"\t\tPath:   \"/foo\",\n" +
+		"\t\tQuery:  \"bar=baz\",\n" +
+		"\t\tHeaders: map[string]string{\n" +
+		"\t\t\t\"Content-Type\": \"application/json\",\n" +
+		"\t\t},\n" +
+		"\t\tBody: `{\"name\": \"billy\"}`,\n" +
+		"\t}).\n" +
+		"\tWillRespondWith(dsl.Response{\n" +
+		"\t\tStatus: 200,\n" +
+		"\t})"
+	if snippet != expected {
+		t.Fatalf("Expected snippet:\n%s\nbut got:\n%s", expected, snippet)
+	}
+}
+
+func TestVerificationError_suggestInteractions(t *testing.T) {
+	err := parseVerificationError(verificationFailure)
+	err.suggestInteractions(nil)
+	if len(err.Suggestions) != 1 || !strings.Contains(err.Suggestions[0], `Method: "DELETE"`) {
+		t.Fatalf("Expected a suggestion for the unexpected request but got: %v", err.Suggestions)
+	}
+	if !strings.Contains(err.Error(), err.Suggestions[0]) {
+		t.Fatalf("Expected error to include the suggestion but got: %s", err.Error())
+	}
+
+	err = parseVerificationError(verificationFailure)
+	err.suggestInteractions([]ReceivedRequest{
+		{Method: "GET", Path: "/bar", Mismatch: "No interaction found for GET /bar"},
+		{Method: "GET", Path: "/bar", Mismatch: "No interaction found for GET /bar"},
+		{Method: "GET", Path: "/foo", Interaction: "A request for foo"},
+	})
+	if len(err.Suggestions) != 1 || !strings.Contains(err.Suggestions[0], `Path:   "/bar"`) {
+		t.Fatalf("Expected a suggestion for the unmatched request but got: %v", err.Suggestions)
+	}
+}
+
+func TestPact_VerifySuggestsInteractions(t *testing.T) {
+	pact, cleanup := setupRecordingPact("Unexpected requests:\n\tPOST /unknown\n", t)
+	defer cleanup()
+
+	err := pact.Verify(func() error {
+		res, err := http.Post(pact.MockServerURL()+"/unknown", "application/json", strings.NewReader(`{"name": "billy"}`))
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	})
+
+	verr, ok := err.(*VerificationError)
+	if !ok {
+		t.Fatalf("Expected a *VerificationError but got: %v", err)
+	}
+	if len(verr.Suggestions) != 1 || !strings.Contains(verr.Suggestions[0], "Body: `{\"name\": \"billy\"}`") {
+		t.Fatalf("Expected a suggestion with the recorded body but got: %v", verr.Suggestions)
+	}
+}
+
+func TestPact_VerifySuggestsInteractionsTestFailed(t *testing.T) {
+	pact, cleanup := setupRecordingPact("Unexpected requests:\n\tPOST /unknown\n", t)
+	defer cleanup()
+
+	err := pact.Verify(func() error {
+		res, err := http.Post(pact.MockServerURL()+"/unknown", "application/json", strings.NewReader(`{"name": "billy"}`))
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	})
+
+	verr, ok := err.(*VerificationError)
+	if !ok {
+		t.Fatalf("Expected a *VerificationError but got: %v", err)
+	}
+	if len(verr.Suggestions) != 1 || !strings.Contains(verr.Suggestions[0], "Body: `{\"name\": \"billy\"}`") {
+		t.Fatalf("Expected a suggestion with the recorded body but got: %v", verr.Suggestions)
+	}
+	if verr.Err == nil || !strings.Contains(verr.Error(), "The test failed with: unexpected status 500") {
+		t.Fatalf("Expected the error of the test to be kept but got: %v", verr)
+	}
+}
+
+func TestPact_VerifyTestFailedWithoutMismatches(t *testing.T) {
+	pact, cleanup := setupRecordingPact("", t)
+	defer cleanup()
+
+	err := pact.Verify(func() error {
+		return errors.New("oh noes")
+	})
+	if _, ok := err.(*VerificationError); ok || err.Error() != "oh noes" {
+		t.Fatalf("Expected the error of the test but got: %v", err)
+	}
+}
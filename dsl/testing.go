@@ -0,0 +1,145 @@
+package dsl
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"testing"
+)
+
+// verifiedPacts are the Pacts used with VerifyT or forked by ForTest, whose
+// pact files are written, and which are torn down, by RunTests.
+var verifiedPacts = struct {
+	sync.Mutex
+	pacts []*Pact
+}{}
+
+// runningTests is set by RunTests, without which the pact files of the Pacts
+// used with VerifyT or forked by ForTest would never be written, nor their
+// Mock Servers stopped.
+var runningTests bool
+
+// VerifyT runs the integration test against the Mock Server, passing it the
+// URL of the Mock Server, and fails the test if the Mock Server could not be
+// started or the interactions were not verified. The interactions are cleared
+// once verified, or if the test fails, so are never carried over to the next
+// test.
+//
+// The Mock Server is left running for the next test. Once all of the tests
+// of the package have run, RunTests writes the pact file, once, and stops the
+// Mock Server, so it must be used in TestMain, or the test fails, e.g.:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(dsl.RunTests(m))
+//	}
+func (p *Pact) VerifyT(t *testing.T, integrationTest func(t *testing.T, mockURL string)) {
+	if !runningTests {
+		t.Fatal("VerifyT requires dsl.RunTests in TestMain")
+	}
+	if err := p.start(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	if !p.forTest {
+		registerVerifiedPact(p)
+	}
+
+	verified := false
+	defer func() {
+		if !verified {
+			p.clearInteractions()
+		}
+	}()
+
+	err := p.Verify(func() error {
+		integrationTest(t, p.MockServerURL())
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	verified = true
+}
+
+// clearInteractions removes the interactions of the Pact, and from the Mock
+// Server, after a failed test.
+func (p *Pact) clearInteractions() {
+	p.Interactions = make([]*Interaction, 0)
+	if p.transport != nil {
+		p.transport.setInteractions(nil)
+	}
+	if p.Server == nil {
+		return
+	}
+
+	mockServer := &MockService{
+		BaseURL:   p.MockServerURL(),
+		TLSConfig: p.MockServerTLSConfig(),
+	}
+	if err := mockServer.DeleteInteractions(); err != nil {
+		log.Println("[WARN] unable to clear interactions:", err)
+	}
+}
+
+// registerVerifiedPact records that the pact file of the Pact is to be
+// written, and the Pact torn down, by RunTests.
+func registerVerifiedPact(p *Pact) {
+	verifiedPacts.Lock()
+	defer verifiedPacts.Unlock()
+	for _, pact := range verifiedPacts.pacts {
+		if pact == p {
+			return
+		}
+	}
+	verifiedPacts.pacts = append(verifiedPacts.pacts, p)
+}
+
+// RunTests runs the tests of a package, then writes the pact files of the
+// Pacts used with VerifyT or forked by ForTest, once for the package, and
+// tears them down, returning the exit code for os.Exit. The exit code is
+// non-zero if any pact file could not be written or Pact torn down.
+func RunTests(m *testing.M) int {
+	runningTests = true
+	code := m.Run()
+	if err := teardownVerifiedPacts(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if code == 0 {
+			code = 1
+		}
+	}
+	return code
+}
+
+// teardownVerifiedPacts writes the pact files of the registered Pacts whose
+// interactions were verified, and tears them all down, returning the first
+// error. The pact files of the Pacts forked by ForTest are written last, as
+// they are merged into that of the Pact they were forked from.
+func teardownVerifiedPacts() error {
+	verifiedPacts.Lock()
+	pacts := verifiedPacts.pacts
+	verifiedPacts.pacts = nil
+	verifiedPacts.Unlock()
+
+	var ordered []*Pact
+	for _, forTest := range []bool{false, true} {
+		for _, p := range pacts {
+			if p.forTest == forTest {
+				ordered = append(ordered, p)
+			}
+		}
+	}
+
+	var first error
+	for _, p := range ordered {
+		if p.verified {
+			if err := p.WritePact(); err != nil && first == nil {
+				first = fmt.Errorf("error writing pact file: %s", err)
+			}
+		}
+		if err := p.Teardown(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,193 @@
+package dsl
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pact-foundation/pact-go/types"
+	"github.com/pact-foundation/pact-go/utils"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(RunTests(m))
+}
+
+func TestPact_VerifyT(t *testing.T) {
+	ms := setupMockServer(true, t)
+	defer ms.Close()
+
+	pact := &Pact{
+		Server:   &types.MockServer{Port: getPort(ms.URL)},
+		Host:     "127.0.0.1",
+		Consumer: "My Consumer",
+		Provider: "My Provider",
+	}
+	defer func() { verifiedPacts.pacts = nil }()
+
+	pact.
+		AddInteraction().
+		UponReceiving("Some name for the test").
+		WithRequest(Request{}).
+		WillRespondWith(Response{})
+
+	var url string
+	pact.VerifyT(t, func(t *testing.T, mockURL string) {
+		url = mockURL
+	})
+
+	if url != pact.MockServerURL() {
+		t.Fatalf("Expected test to be called with %s but got: %s", pact.MockServerURL(), url)
+	}
+	if len(pact.Interactions) != 0 {
+		t.Fatalf("Expected interactions to be cleared but got: %v", pact.Interactions)
+	}
+	if len(verifiedPacts.pacts) != 1 || verifiedPacts.pacts[0] != pact {
+		t.Fatalf("Expected pact to be registered for teardown")
+	}
+
+	// Registering is only done once per Pact
+	pact.VerifyT(t, func(t *testing.T, mockURL string) {})
+	if len(verifiedPacts.pacts) != 1 {
+		t.Fatalf("Expected pact to be registered once but got: %d", len(verifiedPacts.pacts))
+	}
+}
+
+// TestPact_VerifyTHelper fails VerifyT, when run by TestPact_VerifyTFailure.
+func TestPact_VerifyTHelper(t *testing.T) {
+	mode := os.Getenv("GO_WANT_VERIFYT_FAILURE")
+	if mode == "" {
+		return
+	}
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "DELETE" && r.URL.Path == "/interactions":
+			fmt.Println("mock server interactions cleared")
+		case r.URL.Path == "/interactions/verification":
+			http.Error(w, verificationFailure, http.StatusInternalServerError)
+		}
+	}))
+	defer ms.Close()
+
+	pact := &Pact{Server: &types.MockServer{Port: getPort(ms.URL)}, Host: "127.0.0.1"}
+	defer func() { verifiedPacts.pacts = nil }()
+	pact.AddInteraction().UponReceiving("Some name for the test")
+	pact.VerifyT(t, func(t *testing.T, mockURL string) {
+		if mode == "fatal" {
+			t.Fatal("integration test failed")
+		}
+	})
+	fmt.Println("interactions left:", len(pact.Interactions))
+}
+
+func TestPact_VerifyTFailure(t *testing.T) {
+	cases := map[string][]string{
+		"verify": {"Missing requests:", "mock server interactions cleared", "interactions left: 0"},
+		"fatal":  {"integration test failed", "mock server interactions cleared"},
+	}
+
+	for mode, expected := range cases {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestPact_VerifyTHelper$", "-test.v")
+		cmd.Env = append(os.Environ(), "GO_WANT_VERIFYT_FAILURE="+mode)
+		out, err := cmd.CombinedOutput()
+		if err == nil {
+			t.Fatalf("Expected %s test to fail but it passed:\n%s", mode, out)
+		}
+		for _, s := range expected {
+			if !strings.Contains(string(out), s) {
+				t.Fatalf("Expected %s test output to contain '%s' but got:\n%s", mode, s, out)
+			}
+		}
+	}
+}
+
+// TestPact_VerifyTWithoutRunTestsHelper uses VerifyT and ForTest as if
+// RunTests were not used, when run by TestPact_VerifyTWithoutRunTests.
+func TestPact_VerifyTWithoutRunTestsHelper(t *testing.T) {
+	method := os.Getenv("GO_WANT_WITHOUT_RUNTESTS")
+	if method == "" {
+		return
+	}
+	runningTests = false
+
+	pact := &Pact{Consumer: "My Consumer", Provider: "My Provider"}
+	if method == "ForTest" {
+		pact.ForTest(t)
+	} else {
+		pact.VerifyT(t, func(t *testing.T, mockURL string) {})
+	}
+	fmt.Println("test continued")
+}
+
+func TestPact_VerifyTWithoutRunTests(t *testing.T) {
+	for _, method := range []string{"VerifyT", "ForTest"} {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestPact_VerifyTWithoutRunTestsHelper$", "-test.v")
+		cmd.Env = append(os.Environ(), "GO_WANT_WITHOUT_RUNTESTS="+method)
+		out, err := cmd.CombinedOutput()
+		if err == nil {
+			t.Fatalf("Expected %s without RunTests to fail but it passed:\n%s", method, out)
+		}
+		expected := method + " requires dsl.RunTests in TestMain"
+		if !strings.Contains(string(out), expected) || strings.Contains(string(out), "test continued") {
+			t.Fatalf("Expected %s test to fail with '%s' but got:\n%s", method, expected, out)
+		}
+	}
+}
+
+func TestRunTests_teardownVerifiedPacts(t *testing.T) {
+	registerVerifiedPact(&Pact{})
+	registerVerifiedPact(&Pact{})
+	if len(verifiedPacts.pacts) != 2 {
+		t.Fatalf("Expected 2 pacts to be registered but got: %d", len(verifiedPacts.pacts))
+	}
+
+	if err := teardownVerifiedPacts(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(verifiedPacts.pacts) != 0 {
+		t.Fatalf("Expected pacts to be torn down but got: %d", len(verifiedPacts.pacts))
+	}
+}
+
+func TestRunTests_writesPactOnce(t *testing.T) {
+	port, _ := utils.GetFreePort()
+	_, svc := createDaemon(port, true)
+	waitForPortInTest(port, t)
+	defer waitForDaemonToShutdown(port, t)
+
+	var mutex sync.Mutex
+	writes := 0
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" && r.URL.Path == "/pact" {
+			mutex.Lock()
+			writes++
+			mutex.Unlock()
+		}
+	}))
+	defer ms.Close()
+
+	pact := &Pact{Port: port, Server: &types.MockServer{Port: getPort(ms.URL)}, Host: "127.0.0.1", Consumer: "My Consumer", Provider: "My Provider"}
+	for i := 0; i < 2; i++ {
+		pact.AddInteraction().UponReceiving(fmt.Sprintf("Test %d", i))
+		pact.VerifyT(t, func(t *testing.T, mockURL string) {})
+	}
+	if writes != 0 {
+		t.Fatalf("Expected no pact file to be written by the tests but got: %d", writes)
+	}
+
+	if err := teardownVerifiedPacts(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if writes != 1 {
+		t.Fatalf("Expected the pact file to be written once but got: %d", writes)
+	}
+	if svc.ServiceStopCount != 1 {
+		t.Fatalf("Expected the mock server to be stopped but got: %d", svc.ServiceStopCount)
+	}
+}
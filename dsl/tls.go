@@ -0,0 +1,85 @@
+package dsl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/pact-foundation/pact-go/utils"
+)
+
+// setupTLS prepares the certificate and key the Mock Server is served over
+// HTTPS with, generating a self-signed certificate if none was given, and
+// returns the Mock Server arguments to use them.
+func (p *Pact) setupTLS() ([]string, error) {
+	if !p.SSL {
+		return nil, nil
+	}
+	if (p.SSLCert == "") != (p.SSLKey == "") {
+		return nil, errors.New("both an SSLCert and SSLKey are required")
+	}
+
+	certFile, keyFile := p.SSLCert, p.SSLKey
+	if certFile == "" {
+		dir, err := ioutil.TempDir("", "pact-go-ssl")
+		if err != nil {
+			return nil, fmt.Errorf("unable to create certificate directory: %s", err)
+		}
+		p.sslDir = dir
+
+		cert, key, err := utils.GenerateCertificate([]string{p.Host, "localhost", "127.0.0.1", "::1"})
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate certificate: %s", err)
+		}
+		certFile = filepath.Join(dir, "server.crt")
+		keyFile = filepath.Join(dir, "server.key")
+		if err = ioutil.WriteFile(certFile, cert, 0600); err == nil {
+			err = ioutil.WriteFile(keyFile, key, 0600)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to write certificate: %s", err)
+		}
+		log.Println("[DEBUG] generated mock server certificate:", certFile)
+	}
+
+	pem, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read SSLCert: %s", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in SSLCert: %s", certFile)
+	}
+	p.sslCAs = pool
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load SSLCert and SSLKey: %s", err)
+	}
+	p.sslCertificate = &cert
+
+	return []string{"--ssl", "--sslcert", filepath.FromSlash(certFile), "--sslkey", filepath.FromSlash(keyFile)}, nil
+}
+
+// removeTLS removes any certificate generated for the Mock Server.
+func (p *Pact) removeTLS() {
+	if p.sslDir != "" {
+		os.RemoveAll(p.sslDir)
+		p.sslDir = ""
+	}
+}
+
+// MockServerTLSConfig returns the TLS configuration for clients of the Mock
+// Server, trusting its certificate, or nil if it is not served over HTTPS.
+// It is only available once the Mock Server has been started.
+func (p *Pact) MockServerTLSConfig() *tls.Config {
+	if !p.SSL || p.sslCAs == nil {
+		return nil
+	}
+	return &tls.Config{RootCAs: p.sslCAs}
+}
@@ -0,0 +1,170 @@
+package dsl
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pact-foundation/pact-go/types"
+	"github.com/pact-foundation/pact-go/utils"
+)
+
+// serverCertificate returns the certificate of a TLS httptest.Server.
+func serverCertificate(ts *httptest.Server) *x509.Certificate {
+	cert, _ := x509.ParseCertificate(ts.TLS.Certificates[0].Certificate[0])
+	return cert
+}
+
+func TestPact_setupTLS(t *testing.T) {
+	pact := &Pact{SSL: true, Host: "localhost"}
+	args, err := pact.setupTLS()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	dir := pact.sslDir
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	expected := []string{"--ssl", "--sslcert", certFile, "--sslkey", keyFile}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("Expected args %v but got: %v", expected, args)
+	}
+	for _, f := range []string{certFile, keyFile} {
+		if _, err := os.Stat(f); err != nil {
+			t.Fatalf("Expected %s to be generated but got: %v", f, err)
+		}
+	}
+	if pact.MockServerTLSConfig() == nil {
+		t.Fatalf("Expected a TLS config for the mock server")
+	}
+
+	pact.removeTLS()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("Expected certificate directory to be removed but got: %v", err)
+	}
+}
+
+func TestPact_setupTLSDisabled(t *testing.T) {
+	pact := &Pact{Host: "localhost"}
+	args, err := pact.setupTLS()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if args != nil {
+		t.Fatalf("Expected no args but got: %v", args)
+	}
+	if pact.MockServerTLSConfig() != nil {
+		t.Fatalf("Expected no TLS config for the mock server")
+	}
+}
+
+func TestPact_setupTLSMissingKey(t *testing.T) {
+	pact := &Pact{SSL: true, Host: "localhost", SSLCert: "server.crt"}
+	_, err := pact.setupTLS()
+	if err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+}
+
+func TestPact_setupTLSSuppliedCertificate(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pact-go-test")
+	defer os.RemoveAll(dir)
+	cert, key, err := utils.GenerateCertificate([]string{"localhost"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	ioutil.WriteFile(certFile, cert, 0600)
+	ioutil.WriteFile(keyFile, key, 0600)
+
+	pact := &Pact{SSL: true, Host: "localhost", SSLCert: certFile, SSLKey: keyFile}
+	args, err := pact.setupTLS()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	expected := []string{"--ssl", "--sslcert", certFile, "--sslkey", keyFile}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("Expected args %v but got: %v", expected, args)
+	}
+	if pact.sslDir != "" {
+		t.Fatalf("Expected no certificate to be generated")
+	}
+
+	pact = &Pact{SSL: true, Host: "localhost", SSLCert: keyFile, SSLKey: keyFile}
+	if _, err = pact.setupTLS(); err == nil {
+		t.Fatalf("Expected error for a certificate file without certificates")
+	}
+}
+
+func TestPact_VerifySSL(t *testing.T) {
+	ms := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ms.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(serverCertificate(ms))
+
+	pact := &Pact{
+		Server:   &types.MockServer{Port: getPort(ms.URL)},
+		Host:     "127.0.0.1",
+		Consumer: "My Consumer",
+		Provider: "My Provider",
+		SSL:      true,
+		sslCAs:   pool,
+	}
+	pact.
+		AddInteraction().
+		UponReceiving("Some name for the test").
+		WithRequest(Request{}).
+		WillRespondWith(Response{})
+
+	err := pact.Verify(func() error {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: pact.MockServerTLSConfig()}}
+		res, err := client.Get(pact.MockServerURL())
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+}
+
+func TestPact_SetupSSL(t *testing.T) {
+	old := waitForPort
+	defer func() { waitForPort = old }()
+	waitForPort = func(context.Context, int, string, string, string) error {
+		return nil
+	}
+	port, _ := utils.GetFreePort()
+	createDaemon(port, true)
+	waitForPortInTest(port, t)
+	defer waitForDaemonToShutdown(port, t)
+
+	pact := &Pact{Port: port, LogLevel: "DEBUG", SSL: true}
+	if err := pact.Setup(true); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	dir := pact.sslDir
+	if dir == "" {
+		t.Fatalf("Expected a certificate to be generated")
+	}
+	if pact.MockServerTLSConfig() == nil {
+		t.Fatalf("Expected a TLS config for the mock server")
+	}
+
+	pact.Teardown()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("Expected certificate directory to be removed but got: %v", err)
+	}
+}
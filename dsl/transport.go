@@ -0,0 +1,367 @@
+package dsl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// memoryTransport is an http.RoundTripper serving the interactions of a Pact
+// in memory, recording the requests it receives to verify them as the Mock
+// Server does.
+type memoryTransport struct {
+	mutex        sync.Mutex
+	interactions []*transportInteraction
+	requests     []ReceivedRequest
+	incorrect    []RequestMismatch
+	unexpected   []RequestMismatch
+
+	// The interactions verified, to write to the pact file.
+	verified []Interaction
+}
+
+// transportInteraction is an interaction served by a memoryTransport, and
+// whether a request has matched it.
+type transportInteraction struct {
+	Interaction
+	matched   bool
+	incorrect bool
+}
+
+// Transport returns an http.RoundTripper that serves the interactions of the
+// Pact in memory, rather than on a Mock Server, so that consumer tests need
+// no Daemon, Mock Server process or port, e.g.:
+//
+//	client := &http.Client{Transport: pact.Transport()}
+//
+// It must be called before any interactions are added. Verify then matches
+// the requests made with the client, which may be to any host, against the
+// interactions as the Mock Server would, and WritePact writes the pact file
+// itself. As no Mock Server is started, Transport can't be used with
+// ForTest or WithProvider, which return an error, nor with SSL or
+// RecordRequests, although ReceivedRequests returns the requests made.
+func (p *Pact) Transport() http.RoundTripper {
+	if p.transport == nil {
+		p.transport = &memoryTransport{}
+	}
+	return p.transport
+}
+
+// start starts the Mock Server, unless the interactions are served in memory
+// by the Transport.
+func (p *Pact) start() error {
+	if p.transport != nil {
+		p.setDefaults()
+		return nil
+	}
+	return p.Setup(true)
+}
+
+// verifyTransport verifies the requests made with the Transport, and clears
+// the interactions once verified.
+func (p *Pact) verifyTransport() error {
+	if verr := p.transport.verify(); verr != nil {
+		p.explainVerificationError(verr)
+		return verr
+	}
+
+	p.Interactions = make([]*Interaction, 0)
+	p.verified = true
+	return nil
+}
+
+// setInteractions replaces the interactions served, clearing the requests
+// received.
+func (t *memoryTransport) setInteractions(interactions []*Interaction) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.interactions = make([]*transportInteraction, len(interactions))
+	for i, interaction := range interactions {
+		t.interactions[i] = &transportInteraction{Interaction: *interaction}
+	}
+	t.requests = nil
+	t.incorrect = nil
+	t.unexpected = nil
+}
+
+// receivedRequests returns the requests received since the interactions were
+// set.
+func (t *memoryTransport) receivedRequests() []ReceivedRequest {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]ReceivedRequest{}, t.requests...)
+}
+
+// verifiedInteractions returns the interactions verified so far.
+func (t *memoryTransport) verifiedInteractions() []Interaction {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]Interaction{}, t.verified...)
+}
+
+// RoundTrip responds to the request with the interaction it matches, or with
+// a 500 error, as the Mock Server does, if it matches none.
+func (t *memoryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	received := ReceivedRequest{
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Query:   req.URL.RawQuery,
+		Headers: req.Header,
+		Body:    string(body),
+	}
+	log.Println("[DEBUG] pact transport request:", req.Method, req.URL.RequestURI())
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var matches []*transportInteraction
+	var candidates []*transportInteraction
+	var fields []string
+	var fieldDiffs []FieldMismatch
+	var diffs []map[string]interface{}
+	for _, i := range t.interactions {
+		if !strings.EqualFold(i.Request.Method, req.Method) || !pathMatches(i.Request.Path, req.URL.Path) {
+			continue
+		}
+		f, d := diffRequest(i.Request, received)
+		if len(f) == 0 {
+			matches = append(matches, i)
+			continue
+		}
+		if len(candidates) == 0 {
+			fields, fieldDiffs = f, d
+		}
+		candidates = append(candidates, i)
+		var mismatches []string
+		for _, diff := range d {
+			mismatches = append(mismatches, diff.String())
+		}
+		diffs = append(diffs, map[string]interface{}{"description": i.Description, "mismatches": mismatches})
+	}
+
+	mismatch := RequestMismatch{Method: req.Method, Path: req.URL.RequestURI()}
+	switch {
+	case len(matches) == 1:
+		matches[0].matched = true
+		received.Status = responseStatus(matches[0].Response)
+		received.Interaction = matches[0].Description
+		t.requests = append(t.requests, received)
+		return interactionResponse(req, matches[0].Response), nil
+	case len(matches) > 1:
+		received.Mismatch = fmt.Sprintf("Multiple interactions found for %s", mismatch)
+		t.unexpected = append(t.unexpected, mismatch)
+	case len(candidates) > 0:
+		received.Mismatch = fmt.Sprintf("No interaction found for %s", mismatch)
+		mismatch.Fields = fields
+		mismatch.Diffs = fieldDiffs
+		received.Diffs = fieldDiffs
+		t.incorrect = append(t.incorrect, mismatch)
+		for _, i := range candidates {
+			i.incorrect = true
+		}
+	default:
+		received.Mismatch = fmt.Sprintf("No interaction found for %s", mismatch)
+		t.unexpected = append(t.unexpected, mismatch)
+	}
+
+	received.Status = http.StatusInternalServerError
+	t.requests = append(t.requests, received)
+	data, _ := json.Marshal(map[string]interface{}{
+		"message":           received.Mismatch,
+		"interaction_diffs": diffs,
+	})
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	return newResponse(req, http.StatusInternalServerError, header, data), nil
+}
+
+// verify returns a *VerificationError if the requests received did not match
+// the interactions, or else records the interactions as verified.
+func (t *memoryTransport) verify() *VerificationError {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if verr := t.mismatchesLocked(); verr != nil {
+		return verr
+	}
+
+	for _, i := range t.interactions {
+		t.verified = append(t.verified, i.Interaction)
+	}
+	t.interactions = nil
+	t.requests = nil
+	return nil
+}
+
+// mismatches returns a *VerificationError if the requests received so far do
+// not match the interactions, without verifying them.
+func (t *memoryTransport) mismatches() *VerificationError {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.mismatchesLocked()
+}
+
+// mismatchesLocked returns a *VerificationError if the requests received do
+// not match the interactions. The mutex must be held.
+func (t *memoryTransport) mismatchesLocked() *VerificationError {
+	var missing []RequestMismatch
+	for _, i := range t.interactions {
+		if !i.matched && !i.incorrect {
+			path := stringExample(i.Request.Path)
+			if i.Request.Query != "" {
+				path += "?" + stringExample(i.Request.Query)
+			}
+			missing = append(missing, RequestMismatch{Method: strings.ToUpper(i.Request.Method), Path: path})
+		}
+	}
+
+	if len(t.incorrect) > 0 || len(missing) > 0 || len(t.unexpected) > 0 {
+		return &VerificationError{
+			Incorrect:  append([]RequestMismatch{}, t.incorrect...),
+			Missing:    missing,
+			Unexpected: append([]RequestMismatch{}, t.unexpected...),
+			Message:    "Actual interactions do not match expected interactions for the Transport.",
+		}
+	}
+	return nil
+}
+
+// diffRequest returns the fields of the received request that do not match
+// the expected request, other than its method and path, and the differences.
+func diffRequest(expected Request, received ReceivedRequest) ([]string, []FieldMismatch) {
+	var fields []string
+	var diffs []FieldMismatch
+
+	if expected.Query != "" && !queryMatches(expected.Query, received.Query) {
+		fields = append(fields, "query")
+		diffs = append(diffs, FieldMismatch{Path: "$.query", Expected: fmt.Sprintf("%q", stringExample(expected.Query)), Actual: fmt.Sprintf("%q", received.Query)})
+	}
+
+	var headerDiffs []FieldMismatch
+	for _, name := range sortedHeaderNames(expected.Headers) {
+		values, ok := received.Headers[http.CanonicalHeaderKey(name)]
+		value := strings.Join(values, ", ")
+		if !ok || !stringMatches(expected.Headers[name], value) {
+			diff := FieldMismatch{Path: "$.headers." + name, Expected: fmt.Sprintf("%q", stringExample(expected.Headers[name]))}
+			if ok {
+				diff.Actual = fmt.Sprintf("%q", value)
+			}
+			headerDiffs = append(headerDiffs, diff)
+		}
+	}
+	if len(headerDiffs) > 0 {
+		fields = append(fields, "headers")
+		diffs = append(diffs, headerDiffs...)
+	}
+
+	if bodyDiffs := diffBody(expected.Body, received.Body); len(bodyDiffs) > 0 {
+		fields = append(fields, "body")
+		diffs = append(diffs, bodyDiffs...)
+	}
+
+	return fields, diffs
+}
+
+// queryMatches reports whether the query string received matches the one
+// expected, in any order of its parameters, or the Term it is.
+func queryMatches(expected string, query string) bool {
+	if _, _, ok := stringTerm(expected); ok {
+		return stringMatches(expected, query)
+	}
+	e, err := url.ParseQuery(expected)
+	if err != nil {
+		return expected == query
+	}
+	q, err := url.ParseQuery(query)
+	return err == nil && reflect.DeepEqual(e, q)
+}
+
+// diffBody returns the differences of the body received from the body
+// expected, if any is.
+func diffBody(expected interface{}, body string) []FieldMismatch {
+	if expected == nil {
+		return nil
+	}
+	e := toJSON(expected)
+	if s, ok := e.(string); ok {
+		if s != body {
+			return []FieldMismatch{{Path: "$.body", Expected: fmt.Sprintf("%q", s), Actual: fmt.Sprintf("%q", body)}}
+		}
+		return nil
+	}
+
+	var actual interface{}
+	if err := json.Unmarshal([]byte(body), &actual); err != nil {
+		return []FieldMismatch{{Path: "$.body", Expected: "JSON", Actual: fmt.Sprintf("%q", body)}}
+	}
+	return diffValue(e, actual, "$.body", false)
+}
+
+// responseStatus is the status of the response, which defaults to 200.
+func responseStatus(response Response) int {
+	if response.Status == 0 {
+		return http.StatusOK
+	}
+	return response.Status
+}
+
+// interactionResponse returns the response of an interaction to the request,
+// generating any values of its matchers.
+func interactionResponse(req *http.Request, response Response) *http.Response {
+	header := make(http.Header)
+	for name, value := range response.Headers {
+		header.Set(name, stringExample(value))
+	}
+
+	var data []byte
+	if response.Body != nil {
+		switch body := reify(toJSON(response.Body)).(type) {
+		case string:
+			data = []byte(body)
+		default:
+			data, _ = json.Marshal(body)
+		}
+	}
+	return newResponse(req, responseStatus(response), header, data)
+}
+
+// newResponse returns an HTTP/1.1 response to the request.
+func newResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// sortedHeaderNames returns the names of the headers in order.
+func sortedHeaderNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
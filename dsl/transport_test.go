@@ -0,0 +1,537 @@
+package dsl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pact-foundation/pact-go/daemon"
+)
+
+func createTransportPact(t *testing.T) (*Pact, *http.Client, string) {
+	dir, err := ioutil.TempDir("", "pact-go-test")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	pact := &Pact{Consumer: "My Consumer", Provider: "My Provider", PactDir: dir, LogLevel: "ERROR"}
+	client := &http.Client{Transport: pact.Transport()}
+	return pact, client, dir
+}
+
+func TestPact_Transport(t *testing.T) {
+	pact, client, dir := createTransportPact(t)
+	defer os.RemoveAll(dir)
+
+	pact.
+		AddInteraction().
+		Given("User billy exists").
+		UponReceiving("A request to login").
+		WithRequest(Request{
+			Method:  "POST",
+			Path:    Term("/users/login/1", `\\/users\\/login\\/\\d+`),
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    fmt.Sprintf(`{"username": %s}`, Like(`"billy"`)),
+		}).
+		WillRespondWith(Response{
+			Status:  200,
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    fmt.Sprintf(`{"user": {"name": %s, "roles": %s}}`, Like(`"billy"`), EachLike(`"admin"`, 1)),
+		})
+
+	var body string
+	err := pact.Verify(func() error {
+		res, err := client.Post(pact.MockServerURL()+"/users/login/10", "application/json", strings.NewReader(`{"username": "sally"}`))
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != 200 {
+			return fmt.Errorf("unexpected status %d", res.StatusCode)
+		}
+		data, err := ioutil.ReadAll(res.Body)
+		body = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if body != `{"user":{"name":"billy","roles":["admin"]}}` {
+		t.Fatalf("Expected the generated response body but got: %s", body)
+	}
+	if pact.Server != nil || pact.pactClient != nil {
+		t.Fatalf("Expected no mock server or daemon to be used")
+	}
+	if len(pact.Interactions) != 0 {
+		t.Fatalf("Expected interactions to be cleared once verified")
+	}
+
+	if err = pact.WritePact(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "my_consumer-my_provider.json"))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	var file struct {
+		Consumer     map[string]string
+		Interactions []struct {
+			Description   string
+			ProviderState string
+			Request       map[string]interface{}
+			Response      map[string]interface{}
+		}
+		Metadata map[string]string
+	}
+	if err = json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if file.Consumer["name"] != "My Consumer" || file.Metadata["pactSpecificationVersion"] != "2.0.0" || len(file.Interactions) != 1 {
+		t.Fatalf("Expected a pact file with the interaction but got: %s", data)
+	}
+	i := file.Interactions[0]
+	if i.Description != "A request to login" || i.ProviderState != "User billy exists" || i.Request["path"] != "/users/login/1" {
+		t.Fatalf("Expected the interaction in the pact file but got: %s", data)
+	}
+	requestRules := map[string]interface{}{
+		"$.path":          map[string]interface{}{"match": "regex", "regex": `\/users\/login\/\d+`},
+		"$.body.username": map[string]interface{}{"match": "type"},
+	}
+	if !reflect.DeepEqual(i.Request["matchingRules"], requestRules) {
+		t.Fatalf("Expected request matching rules %v but got: %v", requestRules, i.Request["matchingRules"])
+	}
+	responseRules := map[string]interface{}{
+		"$.body.user.name":       map[string]interface{}{"match": "type"},
+		"$.body.user.roles":      map[string]interface{}{"min": float64(1)},
+		"$.body.user.roles[*].*": map[string]interface{}{"match": "type"},
+	}
+	if !reflect.DeepEqual(i.Response["matchingRules"], responseRules) {
+		t.Fatalf("Expected response matching rules %v but got: %v", responseRules, i.Response["matchingRules"])
+	}
+}
+
+func TestPact_TransportVerificationError(t *testing.T) {
+	pact, client, dir := createTransportPact(t)
+	defer os.RemoveAll(dir)
+
+	pact.
+		AddInteraction().
+		UponReceiving("A request for users").
+		WithRequest(Request{Method: "GET", Path: "/users", Query: "name=billy"})
+	pact.
+		AddInteraction().
+		UponReceiving("A request for orders").
+		WithRequest(Request{Method: "GET", Path: "/orders"})
+
+	err := pact.Verify(func() error {
+		for _, path := range []string{"/users?name=sally", "/products"} {
+			res, err := client.Get(pact.MockServerURL() + path)
+			if err != nil {
+				return err
+			}
+			res.Body.Close()
+			if res.StatusCode != http.StatusInternalServerError {
+				return fmt.Errorf("expected a 500 for a request matching no interaction but got: %d", res.StatusCode)
+			}
+		}
+		return errors.New("oh noes")
+	})
+
+	verr, ok := err.(*VerificationError)
+	if !ok {
+		t.Fatalf("Expected a *VerificationError but got: %v", err)
+	}
+	expected := &VerificationError{
+		Incorrect: []RequestMismatch{{Method: "GET", Path: "/users?name=sally", Fields: []string{"query"},
+			Diffs: []FieldMismatch{{Path: "$.query", Expected: `"name=billy"`, Actual: `"name=sally"`}}}},
+		Missing:    []RequestMismatch{{Method: "GET", Path: "/orders"}},
+		Unexpected: []RequestMismatch{{Method: "GET", Path: "/products"}},
+	}
+	if !reflect.DeepEqual(verr.Incorrect, expected.Incorrect) || !reflect.DeepEqual(verr.Missing, expected.Missing) || !reflect.DeepEqual(verr.Unexpected, expected.Unexpected) {
+		t.Fatalf("Expected %v but got: %v", expected, verr)
+	}
+	if verr.Err == nil || verr.Err.Error() != "oh noes" {
+		t.Fatalf("Expected the error of the failed test but got: %v", verr.Err)
+	}
+	if len(verr.Suggestions) != 2 || !strings.Contains(verr.Suggestions[1], `Path:   "/products"`) {
+		t.Fatalf("Expected suggested interactions for the unmatched requests but got: %v", verr.Suggestions)
+	}
+	if len(pact.Interactions) != 2 {
+		t.Fatalf("Expected interactions to be kept if not verified")
+	}
+
+	received, err := pact.ReceivedRequests()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(received) != 2 || received[1].Mismatch != "No interaction found for GET /products" {
+		t.Fatalf("Expected the received requests but got: %+v", received)
+	}
+}
+
+func TestPact_TransportMultipleInteractions(t *testing.T) {
+	pact, client, dir := createTransportPact(t)
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < 2; i++ {
+		pact.
+			AddInteraction().
+			UponReceiving(fmt.Sprintf("A request for users %d", i)).
+			WithRequest(Request{Method: "GET", Path: "/users"})
+	}
+
+	err := pact.Verify(func() error {
+		res, err := client.Get(pact.MockServerURL() + "/users")
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	})
+	verr, ok := err.(*VerificationError)
+	if !ok || len(verr.Unexpected) != 1 || len(verr.Missing) != 2 {
+		t.Fatalf("Expected a request matching several interactions to be unexpected but got: %v", err)
+	}
+}
+
+func TestPact_TransportWritePactMerge(t *testing.T) {
+	pact, client, dir := createTransportPact(t)
+	defer os.RemoveAll(dir)
+	pact.PactFileWriteMode = "merge"
+	fileName := filepath.Join(dir, "my_consumer-my_provider.json")
+	ioutil.WriteFile(fileName, []byte(`{
+		"interactions": [
+			{"description": "A request for orders", "request": {"method": "GET", "path": "/orders"}, "response": {"status": 200}},
+			{"description": "A request for users", "request": {"method": "GET", "path": "/users"}, "response": {"status": 200}}
+		]
+	}`), 0600)
+
+	pact.
+		AddInteraction().
+		UponReceiving("A request for users").
+		WithRequest(Request{Method: "get", Path: "/users"}).
+		WillRespondWith(Response{Status: 200})
+
+	err := pact.Verify(func() error {
+		res, err := client.Get(pact.MockServerURL() + "/users")
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err = pact.WritePact(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	data, _ := ioutil.ReadFile(fileName)
+	var file pactFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	var paths []string
+	for _, raw := range file.Interactions {
+		var i pactInteraction
+		json.Unmarshal(raw, &i)
+		paths = append(paths, i.Request.Method+" "+i.Request.Path)
+	}
+	if expected := []string{"GET /orders", "GET /users"}; !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("Expected interactions %v to be merged but got: %v", expected, paths)
+	}
+}
+
+func TestPact_TransportWritePactMergeConflict(t *testing.T) {
+	pact, client, dir := createTransportPact(t)
+	defer os.RemoveAll(dir)
+	pact.PactFileWriteMode = "merge"
+	fileName := filepath.Join(dir, "my_consumer-my_provider.json")
+	existing := []byte(`{
+		"interactions": [
+			{"description": "A request for users", "request": {"method": "GET", "path": "/old"}, "response": {"status": 200}}
+		]
+	}`)
+	ioutil.WriteFile(fileName, existing, 0600)
+
+	pact.
+		AddInteraction().
+		UponReceiving("A request for users").
+		WithRequest(Request{Method: "GET", Path: "/users"}).
+		WillRespondWith(Response{Status: 200})
+
+	err := pact.Verify(func() error {
+		res, err := client.Get(pact.MockServerURL() + "/users")
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	err = pact.WritePact()
+	if err == nil || !strings.Contains(err.Error(), `"A request for users"`) {
+		t.Fatalf("Expected an error merging a conflicting interaction but got: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(fileName); string(data) != string(existing) {
+		t.Fatalf("Expected the pact file to be left as it is but got: %s", data)
+	}
+}
+
+func TestPact_TransportMockServerConfig(t *testing.T) {
+	pact, _, dir := createTransportPact(t)
+	defer os.RemoveAll(dir)
+	pact.Host = "::1"
+
+	err := pact.VerifyWithConfig(func(config MockServerConfig) error {
+		if config.BaseURL != "http://[::1]" || config.Port != 0 {
+			return fmt.Errorf("unexpected config: %+v", config)
+		}
+		if config.Client.Transport != pact.Transport() {
+			return fmt.Errorf("expected the client to use the transport")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+}
+
+func TestPact_TransportForTest(t *testing.T) {
+	pact, _, dir := createTransportPact(t)
+	defer os.RemoveAll(dir)
+
+	if _, err := pact.fork(); err == nil || !strings.Contains(err.Error(), "Transport") {
+		t.Fatalf("Expected an error forking a pact using the Transport but got: %v", err)
+	}
+}
+
+func TestPact_TransportWithProvider(t *testing.T) {
+	pact, _, dir := createTransportPact(t)
+	defer os.RemoveAll(dir)
+
+	if _, err := pact.WithProvider("Users"); err == nil || !strings.Contains(err.Error(), "Transport") {
+		t.Fatalf("Expected an error adding a provider to a pact using the Transport but got: %v", err)
+	}
+	if pact.pactClient != nil || pact.Server != nil {
+		t.Fatalf("Expected no daemon client or mock server to be set up")
+	}
+}
+
+// conformanceRequest is a request made by a test comparing the Transport
+// with the Mock Service.
+type conformanceRequest struct {
+	method  string
+	path    string
+	headers map[string]string
+	body    string
+}
+
+// conformanceResult is the outcome of verifying the same interactions and
+// requests with the Mock Service or the Transport.
+type conformanceResult struct {
+	Incorrect    []string
+	Missing      []string
+	Unexpected   []string
+	Diffs        []string
+	Interactions []interface{}
+}
+
+// runConformance adds the interaction to the Pact, makes the requests and
+// verifies them, returning the requests that did not match, the paths of the
+// differences reported and, once verified, the interactions of the pact file.
+func runConformance(t *testing.T, pact *Pact, interaction func(*Interaction), requests []conformanceRequest) conformanceResult {
+	interaction(pact.AddInteraction())
+
+	err := pact.VerifyWithConfig(func(config MockServerConfig) error {
+		for _, r := range requests {
+			req, err := http.NewRequest(r.method, config.BaseURL+r.path, strings.NewReader(r.body))
+			if err != nil {
+				return err
+			}
+			for name, value := range r.headers {
+				req.Header.Set(name, value)
+			}
+			res, err := config.Client.Do(req)
+			if err != nil {
+				return err
+			}
+			res.Body.Close()
+		}
+		return nil
+	})
+
+	var result conformanceResult
+	if err != nil {
+		verr, ok := err.(*VerificationError)
+		if !ok {
+			t.Fatalf("Error: %v", err)
+		}
+		for _, r := range verr.Incorrect {
+			fields := append([]string{}, r.Fields...)
+			sort.Strings(fields)
+			result.Incorrect = append(result.Incorrect, fmt.Sprintf("%s (%s)", r, strings.Join(fields, ", ")))
+			for _, d := range r.Diffs {
+				result.Diffs = append(result.Diffs, d.Path)
+			}
+		}
+		for _, r := range verr.Missing {
+			result.Missing = append(result.Missing, r.String())
+		}
+		for _, r := range verr.Unexpected {
+			result.Unexpected = append(result.Unexpected, r.String())
+		}
+		sort.Strings(result.Diffs)
+		return result
+	}
+
+	if err = pact.WritePact(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(pact.PactDir, pactFileName(pact.Consumer, pact.Provider)))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	var file struct {
+		Interactions []interface{} `json:"interactions"`
+	}
+	if err = json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	result.Interactions = file.Interactions
+	return result
+}
+
+// TestPact_TransportConformance verifies interactions and requests with the
+// Transport, and checks that it matches the requests and writes the pact file
+// as the Mock Service does. The results of the Mock Service are given, so
+// that the Transport is checked without the pact tools, and are checked
+// against the Mock Service itself when pact-mock-service is installed.
+func TestPact_TransportConformance(t *testing.T) {
+	login := func(i *Interaction) {
+		i.Given("User billy exists").
+			UponReceiving("A request to login").
+			WithRequest(Request{
+				Method:  "POST",
+				Path:    Term("/users/login/1", `\\/users\\/login\\/\\d+`),
+				Query:   "remember=true&source=web",
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    fmt.Sprintf(`{"username": %s, "roles": %s}`, Like(`"billy"`), EachLike(`"admin"`, 1)),
+			}).
+			WillRespondWith(Response{
+				Status:  200,
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    fmt.Sprintf(`{"user": {"name": %s, "id": %s}}`, Like(`"billy"`), Term("10", `\\d+`)),
+			})
+	}
+	jsonHeaders := map[string]string{"Content-Type": "application/json"}
+
+	var interactions []interface{}
+	json.Unmarshal([]byte(`[{
+		"description": "A request to login",
+		"providerState": "User billy exists",
+		"request": {
+			"method": "POST",
+			"path": "/users/login/1",
+			"query": "remember=true&source=web",
+			"headers": {"Content-Type": "application/json"},
+			"body": {"username": "billy", "roles": ["admin"]},
+			"matchingRules": {
+				"$.path": {"match": "regex", "regex": "\\/users\\/login\\/\\d+"},
+				"$.body.username": {"match": "type"},
+				"$.body.roles": {"min": 1},
+				"$.body.roles[*].*": {"match": "type"}
+			}
+		},
+		"response": {
+			"status": 200,
+			"headers": {"Content-Type": "application/json"},
+			"body": {"user": {"name": "billy", "id": "10"}},
+			"matchingRules": {
+				"$.body.user.name": {"match": "type"},
+				"$.body.user.id": {"match": "regex", "regex": "\\d+"}
+			}
+		}
+	}]`), &interactions)
+
+	incorrect := func(path string, field string, diffs ...string) conformanceResult {
+		return conformanceResult{Incorrect: []string{fmt.Sprintf("POST %s (%s)", path, field)}, Diffs: diffs}
+	}
+	cases := []struct {
+		name     string
+		requests []conformanceRequest
+		expected conformanceResult
+	}{
+		{"matching", []conformanceRequest{
+			{"POST", "/users/login/10?source=web&remember=true", jsonHeaders, `{"username": "sally", "roles": ["user", "admin"]}`},
+		}, conformanceResult{Interactions: interactions}},
+		{"incorrect header", []conformanceRequest{
+			{"POST", "/users/login/10?remember=true&source=web", map[string]string{"Content-Type": "text/plain"}, `{"username": "sally", "roles": ["user"]}`},
+		}, incorrect("/users/login/10?remember=true&source=web", "headers", "$.headers.Content-Type")},
+		{"incorrect query", []conformanceRequest{
+			{"POST", "/users/login/10?remember=false&source=web", jsonHeaders, `{"username": "sally", "roles": ["user"]}`},
+		}, incorrect("/users/login/10?remember=false&source=web", "query", "$.query")},
+		{"incorrect body type", []conformanceRequest{
+			{"POST", "/users/login/10?remember=true&source=web", jsonHeaders, `{"username": 1, "roles": ["user"]}`},
+		}, incorrect("/users/login/10?remember=true&source=web", "body", "$.body.username")},
+		{"empty array", []conformanceRequest{
+			{"POST", "/users/login/10?remember=true&source=web", jsonHeaders, `{"username": "sally", "roles": []}`},
+		}, incorrect("/users/login/10?remember=true&source=web", "body", "$.body.roles")},
+		{"unexpected body key", []conformanceRequest{
+			{"POST", "/users/login/10?remember=true&source=web", jsonHeaders, `{"username": "sally", "roles": ["user"], "admin": true}`},
+		}, incorrect("/users/login/10?remember=true&source=web", "body", "$.body.admin")},
+		{"missing", nil, conformanceResult{Missing: []string{"POST /users/login/1?remember=true&source=web"}}},
+		{"unexpected", []conformanceRequest{
+			{"POST", "/users/login/10?remember=true&source=web", jsonHeaders, `{"username": "sally", "roles": ["user"]}`},
+			{"GET", "/users/billy", nil, ""},
+		}, conformanceResult{Unexpected: []string{"GET /users/billy"}}},
+		{"path not matching", []conformanceRequest{
+			{"POST", "/users/login/billy?remember=true&source=web", jsonHeaders, `{"username": "sally", "roles": ["user"]}`},
+		}, conformanceResult{Missing: []string{"POST /users/login/1?remember=true&source=web"}, Unexpected: []string{"POST /users/login/billy?remember=true&source=web"}}},
+	}
+
+	_, toolErr := daemon.FindTool(daemon.MockServiceTool, os.Getenv("PACT_MOCK_SERVICE_PATH"), os.Getenv("PACT_BIN_DIR"))
+	if toolErr != nil {
+		t.Log("Not comparing with the Mock Service:", toolErr)
+	}
+
+	for _, c := range cases {
+		dir, err := ioutil.TempDir("", "pact-go-test")
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+
+		transport := &Pact{Consumer: "My Consumer", Provider: "My Provider", PactDir: filepath.Join(dir, "transport"), LogLevel: "ERROR"}
+		transport.Transport()
+		if actual := runConformance(t, transport, login, c.requests); !reflect.DeepEqual(actual, c.expected) {
+			t.Fatalf("%s: Expected the Transport to match as the Mock Service does:\n%+v\nbut got:\n%+v", c.name, c.expected, actual)
+		}
+
+		if toolErr == nil {
+			mock := &Pact{
+				Consumer:       "My Consumer",
+				Provider:       "My Provider",
+				PactDir:        filepath.Join(dir, "mock"),
+				LogDir:         dir,
+				LogLevel:       "ERROR",
+				EmbeddedDaemon: true,
+				DaemonLockFile: filepath.Join(dir, "pact-go.lock"),
+				RecordRequests: true,
+			}
+			actual := runConformance(t, mock, login, c.requests)
+			mock.Teardown()
+			if !reflect.DeepEqual(actual, c.expected) {
+				t.Fatalf("%s: Expected the Mock Service to match as given:\n%+v\nbut got:\n%+v", c.name, c.expected, actual)
+			}
+		}
+		os.RemoveAll(dir)
+	}
+}
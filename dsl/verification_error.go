@@ -0,0 +1,317 @@
+package dsl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ANSI colours used to highlight verification errors.
+const (
+	colourRed    = "\x1b[31m"
+	colourGreen  = "\x1b[32m"
+	colourYellow = "\x1b[33m"
+	colourReset  = "\x1b[0m"
+)
+
+// RequestMismatch is a request that was not verified by the Mock Service.
+type RequestMismatch struct {
+	// Method of the request, e.g. "GET".
+	Method string
+
+	// Path of the request, including any query string.
+	Path string
+
+	// Fields of the request that did not match the interaction, e.g.
+	// "headers", "query" or "body". Only set for incorrect requests.
+	Fields []string
+
+	// Diffs are the expected and actual values of each field that did not
+	// match. Only set for incorrect requests, when the differences reported
+	// by the Mock Service are known, i.e. with RecordRequests or Transport.
+	Diffs []FieldMismatch
+}
+
+// String returns the method and path of the request.
+func (r RequestMismatch) String() string {
+	return fmt.Sprintf("%s %s", r.Method, r.Path)
+}
+
+// FieldMismatch is a field of a request that did not match the interaction.
+type FieldMismatch struct {
+	// Path of the field, e.g. "$.query", "$.headers.Accept" or
+	// "$.body.user.name".
+	Path string `json:"path"`
+
+	// Expected value, as JSON, or a description of it such as "a string" or
+	// "a string matching /\d+/". Empty if the field was not expected.
+	Expected string `json:"expected,omitempty"`
+
+	// Actual value received, as JSON. Empty if the field was missing.
+	Actual string `json:"actual,omitempty"`
+}
+
+// String returns the path of the field with its expected and actual values.
+func (f FieldMismatch) String() string {
+	switch {
+	case f.Expected == "":
+		return fmt.Sprintf("%s: unexpected %s", f.Path, f.Actual)
+	case f.Actual == "":
+		return fmt.Sprintf("%s: expected %s but missing", f.Path, f.Expected)
+	}
+	return fmt.Sprintf("%s: expected %s but got %s", f.Path, f.Expected, f.Actual)
+}
+
+// VerificationError is returned by Verify when the requests received by the
+// Mock Service do not match its interactions.
+type VerificationError struct {
+	// Incorrect requests were received for an interaction, but did not match
+	// it in every field.
+	Incorrect []RequestMismatch
+
+	// Missing requests were expected by an interaction, but not received.
+	Missing []RequestMismatch
+
+	// Unexpected requests were received, but matched no interaction.
+	Unexpected []RequestMismatch
+
+	// Message is the failure as reported by the Mock Service.
+	Message string
+
+	// Suggestions are Go snippets adding an interaction for each request that
+	// matched no interaction, to be pasted into the test.
+	Suggestions []string
+
+	// Colour highlights the differences in Error with ANSI colours.
+	Colour bool
+
+	// Err is the error returned by the integration test, if it failed, e.g.
+	// as the Mock Service responded with an error to an unexpected request.
+	Err error
+}
+
+// Error returns a summary of the requests that did not verify, as a diff of
+// the expected (-) and received (+) requests.
+func (e *VerificationError) Error() string {
+	var b bytes.Buffer
+	b.WriteString("Pact verification failed, the requests received by the Mock Service do not match its interactions:\n")
+	if e.Err != nil {
+		fmt.Fprintf(&b, "\nThe test failed with: %s\n", e.Err)
+	}
+
+	if len(e.Incorrect) > 0 {
+		b.WriteString("\nIncorrect requests:\n")
+		for _, r := range e.Incorrect {
+			e.writeLine(&b, colourYellow, "~", fmt.Sprintf("%s (%s did not match)", r, strings.Join(r.Fields, ", ")))
+			for _, d := range r.Diffs {
+				fmt.Fprintf(&b, "\t    %s\n", d)
+			}
+		}
+	}
+	if len(e.Missing) > 0 {
+		b.WriteString("\nMissing requests:\n")
+		for _, r := range e.Missing {
+			e.writeLine(&b, colourRed, "-", r.String())
+		}
+	}
+	if len(e.Unexpected) > 0 {
+		b.WriteString("\nUnexpected requests:\n")
+		for _, r := range e.Unexpected {
+			e.writeLine(&b, colourGreen, "+", r.String())
+		}
+	}
+
+	if len(e.Suggestions) > 0 {
+		b.WriteString("\nTo expect the unmatched requests, add the interactions:\n")
+		for _, s := range e.Suggestions {
+			b.WriteString("\n" + s + "\n")
+		}
+	}
+
+	for _, line := range strings.Split(e.Message, "\n") {
+		if strings.HasPrefix(line, "See ") {
+			b.WriteString("\n" + line + "\n")
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// addDiffs adds the differences recorded for the incorrect requests
+// received, by method and path, to the incorrect requests of the error.
+func (e *VerificationError) addDiffs(received []ReceivedRequest) {
+	for i, r := range e.Incorrect {
+		for _, req := range received {
+			path := req.Path
+			if req.Query != "" {
+				path += "?" + req.Query
+			}
+			if len(req.Diffs) > 0 && strings.EqualFold(req.Method, r.Method) && path == r.Path {
+				e.Incorrect[i].Diffs = req.Diffs
+				break
+			}
+		}
+	}
+}
+
+// writeLine writes a line of the diff, coloured if Colour is set.
+func (e *VerificationError) writeLine(b *bytes.Buffer, colour string, marker string, line string) {
+	if e.Colour {
+		fmt.Fprintf(b, "\t%s%s %s%s\n", colour, marker, line, colourReset)
+		return
+	}
+	fmt.Fprintf(b, "\t%s %s\n", marker, line)
+}
+
+// parseVerificationError parses the failure message of the Mock Service
+// verification, returning nil if it is not in the expected format, e.g.:
+//
+//	Actual interactions do not match expected interactions for mock MockService.
+//
+//	Incorrect requests:
+//		GET /foo (request headers and body did not match)
+//
+//	Missing requests:
+//		GET /bar
+//
+//	See pact.log for details.
+func parseVerificationError(message string) *VerificationError {
+	e := &VerificationError{Message: message}
+	var section *[]RequestMismatch
+	found := false
+
+	for _, line := range strings.Split(message, "\n") {
+		switch strings.TrimSpace(line) {
+		case "Incorrect requests:":
+			section = &e.Incorrect
+		case "Missing requests:":
+			section = &e.Missing
+		case "Unexpected requests:":
+			section = &e.Unexpected
+		default:
+			if section != nil && strings.HasPrefix(line, "\t") {
+				*section = append(*section, parseRequestMismatch(strings.TrimSpace(line)))
+				found = true
+			} else {
+				section = nil
+			}
+		}
+	}
+
+	if !found {
+		return nil
+	}
+	return e
+}
+
+// parseRequestMismatch parses a request summary of the Mock Service, e.g.
+// "GET /foo?bar=baz (request headers, query and body did not match)".
+func parseRequestMismatch(summary string) RequestMismatch {
+	var r RequestMismatch
+	if i := strings.LastIndex(summary, " (request "); i != -1 && strings.HasSuffix(summary, " did not match)") {
+		fields := strings.TrimSuffix(summary[i+len(" (request "):], " did not match)")
+		for _, field := range strings.Split(strings.Replace(fields, " and ", ", ", -1), ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				r.Fields = append(r.Fields, field)
+			}
+		}
+		summary = summary[:i]
+	}
+
+	parts := strings.SplitN(summary, " ", 2)
+	r.Method = parts[0]
+	if len(parts) > 1 {
+		r.Path = parts[1]
+	}
+	return r
+}
+
+// parseInteractionDiffs parses the differences from the closest interaction
+// given by the Mock Service in its response to a request matching none, e.g.:
+//
+//	{
+//	  "message": "No interaction found for GET /foo",
+//	  "interaction_diffs": [{
+//	    "description": "a request for foo",
+//	    "query": {"EXPECTED": "id=1", "ACTUAL": "id=2"},
+//	    "body": {"name": {"EXPECTED_TYPE": "String", "ACTUAL_TYPE": "Integer"}}
+//	  }]
+//	}
+func parseInteractionDiffs(body []byte) []FieldMismatch {
+	var res struct {
+		Diffs []map[string]interface{} `json:"interaction_diffs"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil || len(res.Diffs) == 0 {
+		return nil
+	}
+
+	var diffs []FieldMismatch
+	for _, field := range sortedKeys(res.Diffs[0]) {
+		if field != "description" && field != "provider_state" {
+			diffs = append(diffs, parseDiff(res.Diffs[0][field], "$."+field)...)
+		}
+	}
+	return diffs
+}
+
+// parseDiff parses a difference of the Mock Service at the path, which is
+// either the expected and actual values, or the differences of the fields of
+// an object or the elements of an array.
+func parseDiff(diff interface{}, path string) []FieldMismatch {
+	switch d := diff.(type) {
+	case map[string]interface{}:
+		if _, ok := d["EXPECTED_TYPE"]; ok {
+			return []FieldMismatch{{Path: path, Expected: describeDiffType(d["EXPECTED_TYPE"]), Actual: describeDiffType(d["ACTUAL_TYPE"])}}
+		}
+		if _, ok := d["EXPECTED"]; ok {
+			return []FieldMismatch{{Path: path, Expected: describeDiffValue(d["EXPECTED"]), Actual: describeDiffValue(d["ACTUAL"])}}
+		}
+		var diffs []FieldMismatch
+		for _, key := range sortedKeys(d) {
+			diffs = append(diffs, parseDiff(d[key], keyPath(path, key))...)
+		}
+		return diffs
+	case []interface{}:
+		var diffs []FieldMismatch
+		for i, item := range d {
+			diffs = append(diffs, parseDiff(item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return diffs
+	}
+	// Elements of arrays that did not differ are given as strings
+	return nil
+}
+
+// describeDiffValue describes an expected or actual value of a Mock Service
+// difference, as JSON or a regular expression, or "" if the key was missing
+// or not expected.
+func describeDiffValue(v interface{}) string {
+	if s, ok := v.(string); ok && (s == "<key not found>" || s == "<key not to exist>" || s == "<item not found>" || s == "<item not to exist>") {
+		return ""
+	}
+	if m, ok := v.(map[string]interface{}); ok && m["json_class"] == "Regexp" {
+		return fmt.Sprintf("a string matching /%v/", m["s"])
+	}
+	return describeJSON(v)
+}
+
+// describeDiffType describes the name of a Ruby type in a Mock Service
+// difference as its JSON type, e.g. "a string".
+func describeDiffType(v interface{}) string {
+	switch v {
+	case "String":
+		return "a string"
+	case "Integer", "Fixnum", "Float", "Numeric":
+		return "a number"
+	case "TrueClass", "FalseClass":
+		return "a boolean"
+	case "Array":
+		return "an array"
+	case "Hash":
+		return "an object"
+	case "NilClass":
+		return "null"
+	}
+	return fmt.Sprintf("a %v", v)
+}
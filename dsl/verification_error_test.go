@@ -0,0 +1,146 @@
+package dsl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pact-foundation/pact-go/types"
+)
+
+var verificationFailure = "Actual interactions do not match expected interactions for mock MockService.\n\n" +
+	"Incorrect requests:\n\tGET /foo?bar=baz (request headers, query and body did not match)\n\n" +
+	"Missing requests:\n\tGET /bar\n\tPOST /bar\n\n" +
+	"Unexpected requests:\n\tDELETE /baz\n\n" +
+	"See /tmp/logs/pact.log for details.\n"
+
+func TestVerificationError_parse(t *testing.T) {
+	err := parseVerificationError(verificationFailure)
+	if err == nil {
+		t.Fatalf("Expected verification error to be parsed")
+	}
+
+	incorrect := []RequestMismatch{{Method: "GET", Path: "/foo?bar=baz", Fields: []string{"headers", "query", "body"}}}
+	if !reflect.DeepEqual(err.Incorrect, incorrect) {
+		t.Fatalf("Expected incorrect requests %v but got: %v", incorrect, err.Incorrect)
+	}
+	missing := []RequestMismatch{{Method: "GET", Path: "/bar"}, {Method: "POST", Path: "/bar"}}
+	if !reflect.DeepEqual(err.Missing, missing) {
+		t.Fatalf("Expected missing requests %v but got: %v", missing, err.Missing)
+	}
+	unexpected := []RequestMismatch{{Method: "DELETE", Path: "/baz"}}
+	if !reflect.DeepEqual(err.Unexpected, unexpected) {
+		t.Fatalf("Expected unexpected requests %v but got: %v", unexpected, err.Unexpected)
+	}
+	if err.Message != verificationFailure {
+		t.Fatalf("Expected message to be kept but got: %s", err.Message)
+	}
+}
+
+func TestVerificationError_parseUnknown(t *testing.T) {
+	for _, message := range []string{"", "something went wrong", "Missing requests:\n"} {
+		if err := parseVerificationError(message); err != nil {
+			t.Fatalf("Expected no verification error for '%s' but got: %v", message, err)
+		}
+	}
+}
+
+func TestVerificationError_Error(t *testing.T) {
+	err := parseVerificationError(verificationFailure)
+	expected := "Pact verification failed, the requests received by the Mock Service do not match its interactions:\n\n" +
+		"Incorrect requests:\n\t~ GET /foo?bar=baz (headers, query, body did not match)\n\n" +
+		"Missing requests:\n\t- GET /bar\n\t- POST /bar\n\n" +
+		"Unexpected requests:\n\t+ DELETE /baz\n\n" +
+		"See /tmp/logs/pact.log for details."
+	if err.Error() != expected {
+		t.Fatalf("Expected error '%s' but got '%s'", expected, err.Error())
+	}
+
+	err.Colour = true
+	msg := err.Error()
+	for _, line := range []string{colourRed + "- GET /bar" + colourReset, colourGreen + "+ DELETE /baz" + colourReset, colourYellow + "~ GET /foo"} {
+		if !strings.Contains(msg, line) {
+			t.Fatalf("Expected coloured error to contain '%q' but got '%q'", line, msg)
+		}
+	}
+}
+
+func TestVerificationError_ErrorDiffs(t *testing.T) {
+	err := parseVerificationError(verificationFailure)
+	err.addDiffs([]ReceivedRequest{
+		{Method: "GET", Path: "/foo", Query: "bar=qux"},
+		{Method: "GET", Path: "/foo", Query: "bar=baz", Diffs: []FieldMismatch{
+			{Path: "$.query.bar[0]", Expected: `"qux"`, Actual: `"baz"`},
+			{Path: "$.headers.Accept", Expected: `"application/json"`},
+		}},
+	})
+
+	expected := "Incorrect requests:\n\t~ GET /foo?bar=baz (headers, query, body did not match)\n" +
+		"\t    $.query.bar[0]: expected \"qux\" but got \"baz\"\n" +
+		"\t    $.headers.Accept: expected \"application/json\" but missing\n"
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("Expected error to contain '%s' but got '%s'", expected, err.Error())
+	}
+}
+
+func Test_parseInteractionDiffs(t *testing.T) {
+	body := []byte(`{
+		"message": "No interaction found for POST /foo",
+		"interaction_diffs": [{
+			"description": "A request for foo",
+			"provider_state": "foo exists",
+			"query": {"id": [{"EXPECTED": "1", "ACTUAL": "2"}]},
+			"headers": {"Accept": {"EXPECTED": "application/json", "ACTUAL": "<key not found>"}},
+			"body": {
+				"name": {"EXPECTED_TYPE": "String", "ACTUAL_TYPE": "Integer"},
+				"colour": {"EXPECTED": {"json_class": "Regexp", "o": 0, "s": "red|green"}, "ACTUAL": "blue"},
+				"tags": ["<no difference at this index>", {"EXPECTED": "b", "ACTUAL": "c"}],
+				"extra": {"EXPECTED": "<key not to exist>", "ACTUAL": true}
+			}
+		}, {
+			"description": "Another request for foo",
+			"method": {"EXPECTED": "get", "ACTUAL": "post"}
+		}]
+	}`)
+
+	expected := []FieldMismatch{
+		{Path: "$.body.colour", Expected: "a string matching /red|green/", Actual: `"blue"`},
+		{Path: "$.body.extra", Actual: "true"},
+		{Path: "$.body.name", Expected: "a string", Actual: "a number"},
+		{Path: "$.body.tags[1]", Expected: `"b"`, Actual: `"c"`},
+		{Path: "$.headers.Accept", Expected: `"application/json"`},
+		{Path: "$.query.id[0]", Expected: `"1"`, Actual: `"2"`},
+	}
+	if diffs := parseInteractionDiffs(body); !reflect.DeepEqual(diffs, expected) {
+		t.Fatalf("Expected diffs %v but got: %v", expected, diffs)
+	}
+	if diffs := parseInteractionDiffs([]byte(`{"message": "No interaction found", "interaction_diffs": []}`)); diffs != nil {
+		t.Fatalf("Expected no diffs but got: %v", diffs)
+	}
+}
+
+func TestPact_VerifyVerificationError(t *testing.T) {
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/interactions/verification" {
+			http.Error(w, verificationFailure, http.StatusInternalServerError)
+		}
+	}))
+	defer ms.Close()
+
+	pact := &Pact{
+		Server:       &types.MockServer{Port: getPort(ms.URL)},
+		Host:         "127.0.0.1",
+		ColourOutput: true,
+	}
+	err := pact.Verify(func() error { return nil })
+
+	verr, ok := err.(*VerificationError)
+	if !ok {
+		t.Fatalf("Expected a *VerificationError but got: %v", err)
+	}
+	if len(verr.Missing) != 2 || !verr.Colour {
+		t.Fatalf("Expected coloured error with 2 missing requests but got: %v", verr)
+	}
+}
@@ -0,0 +1,306 @@
+package dsl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pact-foundation/pact-go/types"
+)
+
+var (
+	responseAspectSeparator = " returns a response which "
+	statusAspectRegex       = regexp.MustCompile(`^has status code (\d+)`)
+	headerAspectRegex       = regexp.MustCompile(`^includes headers "(.+?)" which (.*)$`)
+	headerValueRegex        = regexp.MustCompile(`(?:equals|with value) "(.*)"$`)
+	bodyAspect              = "has a matching body"
+	requestRegex            = regexp.MustCompile(` with ([A-Z]+) (\S+)$`)
+	actualStatusRegex       = regexp.MustCompile(`got: (\d+)`)
+	actualHeaderRegex       = regexp.MustCompile(`but was (.*)$`)
+	actualBodyRegex         = regexp.MustCompile(`(?m)^Actual: (.*)$`)
+	differenceRegex         = regexp.MustCompile(`(?m)^\* (.*) at (\$\S*)\s*$`)
+	expectedActualRegex     = regexp.MustCompile(`^Expected (.+?) but got (.+)$`)
+)
+
+// verifiedPact is the subset of a Pact file needed to identify the
+// interactions reported by the verifier.
+type verifiedPact struct {
+	Consumer     PactName              `json:"consumer"`
+	Provider     PactName              `json:"provider"`
+	Interactions []verifiedInteraction `json:"interactions"`
+}
+
+// verifiedInteraction is a single interaction in a verifiedPact.
+type verifiedInteraction struct {
+	Description         string           `json:"description"`
+	ProviderState       string           `json:"providerState"`
+	LegacyProviderState string           `json:"provider_state"`
+	Request             verifiedRequest  `json:"request"`
+	Response            verifiedResponse `json:"response"`
+}
+
+// verifiedRequest is the request of a verifiedInteraction.
+type verifiedRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   json.RawMessage   `json:"query"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// verifiedResponse is the expected response of a verifiedInteraction.
+type verifiedResponse struct {
+	Body json.RawMessage `json:"body"`
+}
+
+// query returns the query string of the request, which is a string in
+// version 2 pacts and an object of parameter values in version 3.
+func (r verifiedRequest) query() string {
+	if len(r.Query) == 0 {
+		return ""
+	}
+	var query string
+	if err := json.Unmarshal(r.Query, &query); err == nil {
+		return query
+	}
+	var values url.Values
+	if err := json.Unmarshal(r.Query, &values); err == nil {
+		return values.Encode()
+	}
+	return ""
+}
+
+// bodyString returns a body of a pact as it is sent, i.e. the JSON, or the
+// text of a string body.
+func bodyString(body json.RawMessage) string {
+	if len(body) == 0 || string(body) == "null" {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(body, &text); err == nil {
+		return text
+	}
+	return string(body)
+}
+
+// fullPath is the path of the request, with its query string if it has one.
+func (r verifiedRequest) fullPath() string {
+	if query := r.query(); query != "" {
+		return r.Path + "?" + query
+	}
+	return r.Path
+}
+
+// state returns the provider state, regardless of the specification version.
+func (i verifiedInteraction) state() string {
+	if i.ProviderState != "" {
+		return i.ProviderState
+	}
+	return i.LegacyProviderState
+}
+
+// heading is how the verifier describes the interaction in its output.
+func (i verifiedInteraction) heading(p verifiedPact) string {
+	heading := fmt.Sprintf("Verifying a pact between %s and %s ", p.Consumer.Name, p.Provider.Name)
+	if i.state() != "" {
+		heading = fmt.Sprintf("%sGiven %s ", heading, i.state())
+	}
+	return fmt.Sprintf("%s%s with %s %s", heading, i.Description, strings.ToUpper(i.Request.Method), i.Request.fullPath())
+}
+
+// readVerifiedPacts reads the interactions from each of the verified pacts.
+// Pacts that cannot be read are skipped.
+func readVerifiedPacts(ctx context.Context, request types.VerifyRequest) []verifiedPact {
+	publisher := &Publisher{
+		request: types.PublishRequest{
+			BrokerUsername: request.BrokerUsername,
+			BrokerPassword: request.BrokerPassword,
+		},
+	}
+
+	var pacts []verifiedPact
+	for _, url := range request.PactURLs {
+		_, data, err := publisher.readPactFile(ctx, url)
+		if err != nil {
+			log.Println("[DEBUG] verification result: unable to read pact", url, err)
+			continue
+		}
+		var pact verifiedPact
+		if err = json.Unmarshal(data, &pact); err != nil {
+			log.Println("[DEBUG] verification result: unable to parse pact", url, err)
+			continue
+		}
+		pacts = append(pacts, pact)
+	}
+
+	return pacts
+}
+
+// parseInteractionResults groups the examples reported by the verifier into
+// a result per interaction, extracting the actual response and mismatches
+// from the (human readable) failure messages.
+func parseInteractionResults(examples []types.ProviderVerifierExample, pacts []verifiedPact) []types.InteractionResult {
+	var results []*types.InteractionResult
+	byHeading := make(map[string]*types.InteractionResult)
+
+	for _, example := range examples {
+		heading := example.FullDescription
+		aspect := ""
+		if i := strings.Index(heading, responseAspectSeparator); i >= 0 {
+			aspect = heading[i+len(responseAspectSeparator):]
+			heading = heading[:i]
+		}
+
+		result, ok := byHeading[heading]
+		if !ok {
+			result = newInteractionResult(heading, pacts)
+			byHeading[heading] = result
+			results = append(results, result)
+		}
+
+		if example.Status != "passed" {
+			result.Passed = false
+		}
+		parseExample(example, aspect, result)
+	}
+
+	parsed := make([]types.InteractionResult, len(results))
+	for i, r := range results {
+		parsed[i] = *r
+	}
+	return parsed
+}
+
+// newInteractionResult creates a result for the interaction with the given
+// heading, filling in the details from the matching pact interaction.
+func newInteractionResult(heading string, pacts []verifiedPact) *types.InteractionResult {
+	result := &types.InteractionResult{
+		Description: heading,
+		Passed:      true,
+	}
+
+	for _, p := range pacts {
+		for _, i := range p.Interactions {
+			if i.heading(p) == heading {
+				result.Consumer = p.Consumer.Name
+				result.Provider = p.Provider.Name
+				result.Description = i.Description
+				result.ProviderState = i.state()
+				result.Request = types.InteractionRequest{
+					Method:  strings.ToUpper(i.Request.Method),
+					Path:    i.Request.Path,
+					Query:   i.Request.query(),
+					Headers: i.Request.Headers,
+					Body:    bodyString(i.Request.Body),
+				}
+				// Replaced by the actual body if the body does not match
+				result.Response.Body = bodyString(i.Response.Body)
+				return result
+			}
+		}
+	}
+
+	if m := requestRegex.FindStringSubmatch(heading); m != nil {
+		path := strings.SplitN(m[2], "?", 2)
+		result.Request.Method = m[1]
+		result.Request.Path = path[0]
+		if len(path) > 1 {
+			result.Request.Query = path[1]
+		}
+	}
+
+	return result
+}
+
+// parseExample adds the details of a single example (status, header or body
+// assertion) to the interaction result.
+func parseExample(example types.ProviderVerifierExample, aspect string, result *types.InteractionResult) {
+	passed := example.Status == "passed"
+	message := strings.TrimSpace(example.Exception.Message)
+
+	switch {
+	case statusAspectRegex.MatchString(aspect):
+		expected := statusAspectRegex.FindStringSubmatch(aspect)[1]
+		actual := expected
+		if !passed {
+			actual = ""
+			if m := actualStatusRegex.FindStringSubmatch(message); m != nil {
+				actual = m[1]
+			}
+			result.Mismatches = append(result.Mismatches, types.Mismatch{
+				Type:     types.MismatchTypeStatus,
+				Expected: expected,
+				Actual:   actual,
+				Message:  message,
+			})
+		}
+		result.Response.Status, _ = strconv.Atoi(actual)
+
+	case headerAspectRegex.MatchString(aspect):
+		m := headerAspectRegex.FindStringSubmatch(aspect)
+		name, expected := m[1], m[2]
+		actual := ""
+		if v := headerValueRegex.FindStringSubmatch(expected); v != nil && passed {
+			actual = v[1]
+		}
+		if !passed {
+			if v := actualHeaderRegex.FindStringSubmatch(message); v != nil && v[1] != "nil" {
+				actual = strings.Trim(v[1], `"`)
+			}
+			result.Mismatches = append(result.Mismatches, types.Mismatch{
+				Type:     types.MismatchTypeHeader,
+				Path:     name,
+				Expected: expected,
+				Actual:   actual,
+				Message:  message,
+			})
+		}
+		if actual != "" {
+			if result.Response.Headers == nil {
+				result.Response.Headers = make(map[string]string)
+			}
+			result.Response.Headers[name] = actual
+		}
+
+	case aspect == bodyAspect:
+		if passed {
+			return
+		}
+		result.Response.Body = ""
+		if m := actualBodyRegex.FindStringSubmatch(message); m != nil {
+			result.Response.Body = m[1]
+		}
+		differences := differenceRegex.FindAllStringSubmatch(message, -1)
+		if len(differences) == 0 {
+			result.Mismatches = append(result.Mismatches, types.Mismatch{
+				Type:    types.MismatchTypeBody,
+				Message: message,
+			})
+		}
+		for _, d := range differences {
+			mismatch := types.Mismatch{
+				Type:    types.MismatchTypeBody,
+				Path:    d[2],
+				Message: d[1],
+			}
+			if m := expectedActualRegex.FindStringSubmatch(d[1]); m != nil {
+				mismatch.Expected = m[1]
+				mismatch.Actual = m[2]
+			}
+			result.Mismatches = append(result.Mismatches, mismatch)
+		}
+
+	default:
+		if !passed {
+			result.Mismatches = append(result.Mismatches, types.Mismatch{
+				Type:    types.MismatchTypeError,
+				Message: message,
+			})
+		}
+	}
+}
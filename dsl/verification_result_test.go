@@ -0,0 +1,197 @@
+package dsl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/pact-foundation/pact-go/types"
+)
+
+var verifiedPactFixture = verifiedPact{
+	Consumer: PactName{Name: "billy"},
+	Provider: PactName{Name: "bobby"},
+	Interactions: []verifiedInteraction{
+		{
+			Description:   "A request to login",
+			ProviderState: "User billy exists",
+		},
+	},
+}
+
+func init() {
+	verifiedPactFixture.Interactions[0].Request = verifiedRequest{
+		Method:  "post",
+		Path:    "/users/login",
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    json.RawMessage(`{"username":"billy"}`),
+	}
+	verifiedPactFixture.Interactions[0].Response.Body = json.RawMessage(`{"firstName":"billy"}`)
+}
+
+func verifierExample(aspect string, status string, message string) types.ProviderVerifierExample {
+	example := types.ProviderVerifierExample{
+		Description:     aspect,
+		FullDescription: fmt.Sprintf("Verifying a pact between billy and bobby Given User billy exists A request to login with POST /users/login returns a response which %s", aspect),
+		Status:          status,
+	}
+	example.Exception.Message = message
+	return example
+}
+
+func TestVerificationResult_parsePassed(t *testing.T) {
+	examples := []types.ProviderVerifierExample{
+		verifierExample("has status code 200", "passed", ""),
+		verifierExample(`includes headers "Content-Type" which equals "application/json"`, "passed", ""),
+		verifierExample("has a matching body", "passed", ""),
+	}
+
+	results := parseInteractionResults(examples, []verifiedPact{verifiedPactFixture})
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 interaction result but got %d", len(results))
+	}
+
+	expected := types.InteractionResult{
+		Consumer:      "billy",
+		Provider:      "bobby",
+		Description:   "A request to login",
+		ProviderState: "User billy exists",
+		Request: types.InteractionRequest{
+			Method:  "POST",
+			Path:    "/users/login",
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    `{"username":"billy"}`,
+		},
+		Response: types.InteractionResponse{
+			Status:  200,
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    `{"firstName":"billy"}`,
+		},
+		Passed: true,
+	}
+	if !reflect.DeepEqual(results[0], expected) {
+		t.Fatalf("Expected %+v but got %+v", expected, results[0])
+	}
+}
+
+func TestVerificationResult_parseFailed(t *testing.T) {
+	examples := []types.ProviderVerifierExample{
+		verifierExample("has status code 200", "failed", "\nexpected: 200\n     got: 401\n\n(compared using eql?)\n"),
+		verifierExample(`includes headers "Content-Type" which equals "application/json"`, "failed", `Expected header "Content-Type" to equal "application/json", but was "text/plain"`),
+		verifierExample("has a matching body", "failed", `Actual: {"lastName":"baz"}
+
+Diff
+--------------------------------------
+Key: - is expected
+     + is actual
+Matching keys and values are not shown
+
+ {
+-  "lastName": "bar"
++  "lastName": "baz"
+ }
+
+Description of differences
+--------------------------------------
+* Expected "bar" but got "baz" at $.lastName
+* Could not find key "firstName" (keys present are: lastName) at $
+`),
+		verifierExample("has a matching body", "failed", "provider state setup failed"),
+	}
+	examples[3].FullDescription = "Verifying a pact between billy and bobby Given User billy exists A request to login with POST /users/login"
+
+	results := parseInteractionResults(examples, []verifiedPact{verifiedPactFixture})
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 interaction result but got %d", len(results))
+	}
+
+	result := results[0]
+	if result.Passed {
+		t.Fatalf("Expected interaction to fail")
+	}
+	if result.Response.Status != 401 {
+		t.Fatalf("Expected actual status 401 but got %d", result.Response.Status)
+	}
+	if result.Response.Headers["Content-Type"] != "text/plain" {
+		t.Fatalf("Expected actual header 'text/plain' but got '%s'", result.Response.Headers["Content-Type"])
+	}
+	if result.Response.Body != `{"lastName":"baz"}` {
+		t.Fatalf("Expected actual body but got '%s'", result.Response.Body)
+	}
+
+	expected := []types.Mismatch{
+		{Type: types.MismatchTypeStatus, Expected: "200", Actual: "401", Message: examples[0].Exception.Message[1 : len(examples[0].Exception.Message)-1]},
+		{Type: types.MismatchTypeHeader, Path: "Content-Type", Expected: `equals "application/json"`, Actual: "text/plain", Message: examples[1].Exception.Message},
+		{Type: types.MismatchTypeBody, Path: "$.lastName", Expected: `"bar"`, Actual: `"baz"`, Message: `Expected "bar" but got "baz"`},
+		{Type: types.MismatchTypeBody, Path: "$", Message: `Could not find key "firstName" (keys present are: lastName)`},
+		{Type: types.MismatchTypeError, Message: "provider state setup failed"},
+	}
+	if !reflect.DeepEqual(result.Mismatches, expected) {
+		t.Fatalf("Expected mismatches %+v but got %+v", expected, result.Mismatches)
+	}
+}
+
+func TestVerificationResult_parseUnknownInteraction(t *testing.T) {
+	examples := []types.ProviderVerifierExample{
+		verifierExample("has status code 200", "passed", ""),
+	}
+
+	results := parseInteractionResults(examples, nil)
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 interaction result but got %d", len(results))
+	}
+	if results[0].Consumer != "" {
+		t.Fatalf("Expected no consumer but got '%s'", results[0].Consumer)
+	}
+	if results[0].Request.Method != "POST" || results[0].Request.Path != "/users/login" {
+		t.Fatalf("Expected request to be parsed from description but got %+v", results[0].Request)
+	}
+}
+
+func TestVerificationResult_readVerifiedPacts(t *testing.T) {
+	file, err := ioutil.TempFile("", "pactgo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(`{"consumer":{"name":"billy"},"provider":{"name":"bobby"},"interactions":[{"description":"A request to login","provider_state":"User billy exists","request":{"method":"post","path":"/users/login"}}]}`)
+	file.Close()
+
+	pacts := readVerifiedPacts(context.Background(), types.VerifyRequest{PactURLs: []string{file.Name(), "does-not-exist.json"}})
+	if len(pacts) != 1 {
+		t.Fatalf("Expected 1 pact but got %d", len(pacts))
+	}
+	if !reflect.DeepEqual(pacts[0].Interactions[0].heading(pacts[0]), verifiedPactFixture.Interactions[0].heading(verifiedPactFixture)) {
+		t.Fatalf("Expected pact interactions to be read")
+	}
+}
+
+func TestVerificationResult_requestWithQuery(t *testing.T) {
+	pact := verifiedPact{
+		Consumer:     PactName{Name: "billy"},
+		Provider:     PactName{Name: "bobby"},
+		Interactions: []verifiedInteraction{{Description: "A request for users"}},
+	}
+	pact.Interactions[0].Request = verifiedRequest{Method: "get", Path: "/users", Query: json.RawMessage(`{"name":["billy"]}`)}
+	examples := []types.ProviderVerifierExample{{
+		FullDescription: "Verifying a pact between billy and bobby A request for users with GET /users?name=billy returns a response which has status code 200",
+		Status:          "passed",
+	}}
+
+	results := parseInteractionResults(examples, []verifiedPact{pact})
+	if len(results) != 1 || results[0].Consumer != "billy" {
+		t.Fatalf("Expected the interaction to be found in the pact but got %+v", results)
+	}
+	if results[0].Request.Path != "/users" || results[0].Request.Query != "name=billy" {
+		t.Fatalf("Expected the request query but got %+v", results[0].Request)
+	}
+
+	results = parseInteractionResults(examples, nil)
+	if results[0].Request.Path != "/users" || results[0].Request.Query != "name=billy" {
+		t.Fatalf("Expected the request query to be parsed from description but got %+v", results[0].Request)
+	}
+}
@@ -21,6 +21,9 @@ type Client struct {
 	user *User
 	Host string
 	err  error
+
+	// HTTPClient calls the User Service, defaulting to http.DefaultClient.
+	HTTPClient *http.Client
 }
 
 // Marshalling format for Users.
@@ -44,7 +47,11 @@ func (c *Client) login(username string, password string) (*User, error) {
       "password": "%s"
     }`, username, password)
 
-	res, err := http.Post(fmt.Sprintf("%s/users/login", c.Host), "application/json; charset=utf-8", bytes.NewReader([]byte(loginRequest)))
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Post(fmt.Sprintf("%s/users/login", c.Host), "application/json; charset=utf-8", bytes.NewReader([]byte(loginRequest)))
 	if res.StatusCode != 200 || err != nil {
 		return nil, fmt.Errorf("login failed")
 	}
@@ -0,0 +1,88 @@
+package types
+
+// Types of Mismatch found during provider verification.
+const (
+	// MismatchTypeStatus is a difference in the HTTP status code.
+	MismatchTypeStatus = "status"
+
+	// MismatchTypeHeader is a difference in an HTTP response header.
+	MismatchTypeHeader = "header"
+
+	// MismatchTypeBody is a difference in the HTTP response body.
+	MismatchTypeBody = "body"
+
+	// MismatchTypeError is a failure that prevented the interaction from being
+	// compared, such as an error setting up a provider state.
+	MismatchTypeError = "error"
+)
+
+// InteractionResult is the structured result of verifying a single interaction
+// from a Pact file against a Provider.
+type InteractionResult struct {
+	// Consumer name. May be empty if the interaction could not be found in a pact.
+	Consumer string `json:"consumer,omitempty"`
+
+	// Provider name. May be empty if the interaction could not be found in a pact.
+	Provider string `json:"provider,omitempty"`
+
+	// Description of the interaction. If the interaction could not be found
+	// in a pact, this is the description reported by the verifier.
+	Description string `json:"description"`
+
+	// ProviderState the interaction was verified in.
+	ProviderState string `json:"providerState,omitempty"`
+
+	// Request sent to the Provider.
+	Request InteractionRequest `json:"request"`
+
+	// Response is the actual response from the Provider, as far as it is
+	// reported by the verifier. The verifier only reports the actual values
+	// that did not match, so for those that did, such as the body of a passing
+	// interaction, it has the values expected by the pact.
+	Response InteractionResponse `json:"response"`
+
+	// Passed is true if the Provider satisfied the interaction.
+	Passed bool `json:"passed"`
+
+	// Mismatches contains all differences between the expected and actual
+	// response.
+	Mismatches []Mismatch `json:"mismatches,omitempty"`
+}
+
+// InteractionRequest is the request sent to a Provider for an interaction,
+// as given in the pact. Query, Headers and Body are empty if the interaction
+// could not be found in a pact.
+type InteractionRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   string            `json:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// InteractionResponse is the actual response returned by a Provider for an
+// interaction.
+type InteractionResponse struct {
+	Status  int               `json:"status,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// Mismatch is a single difference between an expected and actual response.
+type Mismatch struct {
+	// Type of mismatch, one of the MismatchType* constants.
+	Type string `json:"type"`
+
+	// Path is the JSON path for body mismatches, or header name for header
+	// mismatches.
+	Path string `json:"path,omitempty"`
+
+	// Expected value.
+	Expected string `json:"expected,omitempty"`
+
+	// Actual value.
+	Actual string `json:"actual,omitempty"`
+
+	// Message is the human readable description of the mismatch.
+	Message string `json:"message"`
+}
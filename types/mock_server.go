@@ -1,9 +1,45 @@
 package types
 
+// States of a Mock Server during its lifecycle.
+const (
+	// MockServerStarting is a Mock Server whose process is running, but is not
+	// yet accepting connections.
+	MockServerStarting = "starting"
+
+	// MockServerReady is a Mock Server that is accepting connections.
+	MockServerReady = "ready"
+
+	// MockServerExited is a Mock Server whose process has exited.
+	MockServerExited = "exited"
+)
+
 // MockServer contains the RPC client interface to a Mock Server
 type MockServer struct {
-	Pid    int
-	Port   int
+	Pid  int
+	Port int
+
+	// AllowedPorts are the ports, as a list of ports and ranges of ports, the
+	// Daemon may start the Mock Server on if no Port is given. Any free port
+	// is used if neither are given.
+	AllowedPorts string
+
+	// Host the Mock Server port must be free on. Defaults to 'localhost'.
+	Host string
+
+	// Status is -1 whilst the Mock Server is running, and its exit code once
+	// it has exited. StopServer sets it to 0 if the Mock Server stopped
+	// cleanly, and 1 otherwise.
 	Status int
 	Args   []string
+
+	// State is the lifecycle state of the Mock Server, one of
+	// MockServerStarting, MockServerReady or MockServerExited.
+	State string
+
+	// Error is the reason the Mock Server failed to start or stop, if any.
+	Error string
+
+	// Logs is the most recent output of the Mock Server, only returned by
+	// the ServerLogs RPC.
+	Logs []string
 }
@@ -0,0 +1,31 @@
+package types
+
+// MockServerEvent is a lifecycle event of a Mock Server started by the
+// Daemon, returned by the ServerEvents RPC.
+type MockServerEvent struct {
+	// Seq orders the events of a Daemon, increasing by one with each event.
+	Seq int
+
+	// Type is one of "started" (including restarts), "stopped" or "exited".
+	Type string
+
+	// Pid identifies the Mock Server, by the pid it was first started with.
+	Pid int
+
+	// Code is the exit code of a stopped or exited Mock Server.
+	Code int
+
+	// Restarts is the number of times the Mock Server has been restarted.
+	Restarts int
+}
+
+// MockServerEventsRequest requests the lifecycle events of Mock Servers from
+// the Daemon.
+type MockServerEventsRequest struct {
+	// Since is the Seq of the last event seen, so that only later events are
+	// returned. Zero returns all the events the Daemon still holds.
+	Since int
+
+	// Pid, if given, returns only the events of that Mock Server.
+	Pid int
+}
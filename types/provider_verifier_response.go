@@ -3,28 +3,60 @@ package types
 // PactVerifierResponse contains the ouput of the pact-provider-verifier
 // command.
 type ProviderVerifierResponse struct {
-	Version  string `json:"version"`
-	Examples []struct {
-		ID              string      `json:"id"`
-		Description     string      `json:"description"`
-		FullDescription string      `json:"full_description"`
-		Status          string      `json:"status"`
-		FilePath        string      `json:"file_path"`
-		LineNumber      int         `json:"line_number"`
-		RunTime         float64     `json:"run_time"`
-		PendingMessage  interface{} `json:"pending_message"`
-		Exception       struct {
-			Class     string   `json:"class"`
-			Message   string   `json:"message"`
-			Backtrace []string `json:"backtrace"`
-		} `json:"exception,omitempty"`
-	} `json:"examples"`
-	Summary struct {
-		Duration                     float64 `json:"duration"`
-		ExampleCount                 int     `json:"example_count"`
-		FailureCount                 int     `json:"failure_count"`
-		PendingCount                 int     `json:"pending_count"`
-		ErrorsOutsideOfExamplesCount int     `json:"errors_outside_of_examples_count"`
-	} `json:"summary"`
-	SummaryLine string `json:"summary_line"`
+	Version     string                    `json:"version"`
+	Examples    []ProviderVerifierExample `json:"examples"`
+	Summary     ProviderVerifierSummary   `json:"summary"`
+	SummaryLine string                    `json:"summary_line"`
+
+	// Interactions contains the structured result of each verified interaction,
+	// derived from the Examples.
+	Interactions []InteractionResult `json:"interactions,omitempty"`
+
+	// Consumers groups the results by consumer when pacts are verified
+	// concurrently.
+	Consumers []ConsumerVerifierResponse `json:"consumers,omitempty"`
+}
+
+// ConsumerVerifierResponse contains the verification results of the pacts
+// of a single consumer.
+type ConsumerVerifierResponse struct {
+	// Consumer name, or the Pact URL if the pact could not be read.
+	Consumer     string                    `json:"consumer"`
+	PactURLs     []string                  `json:"pact_urls"`
+	Examples     []ProviderVerifierExample `json:"examples"`
+	Summary      ProviderVerifierSummary   `json:"summary"`
+	Interactions []InteractionResult       `json:"interactions,omitempty"`
+
+	// Error running the verification process for the consumer, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// ProviderVerifierExample is a single RSpec example (assertion) from the
+// pact-provider-verifier command.
+type ProviderVerifierExample struct {
+	ID              string                    `json:"id"`
+	Description     string                    `json:"description"`
+	FullDescription string                    `json:"full_description"`
+	Status          string                    `json:"status"`
+	FilePath        string                    `json:"file_path"`
+	LineNumber      int                       `json:"line_number"`
+	RunTime         float64                   `json:"run_time"`
+	PendingMessage  interface{}               `json:"pending_message"`
+	Exception       ProviderVerifierException `json:"exception,omitempty"`
+}
+
+// ProviderVerifierException contains the failure details of an example.
+type ProviderVerifierException struct {
+	Class     string   `json:"class"`
+	Message   string   `json:"message"`
+	Backtrace []string `json:"backtrace"`
+}
+
+// ProviderVerifierSummary contains the totals of a verification run.
+type ProviderVerifierSummary struct {
+	Duration                     float64 `json:"duration"`
+	ExampleCount                 int     `json:"example_count"`
+	FailureCount                 int     `json:"failure_count"`
+	PendingCount                 int     `json:"pending_count"`
+	ErrorsOutsideOfExamplesCount int     `json:"errors_outside_of_examples_count"`
 }
@@ -2,6 +2,8 @@ package types
 
 import (
 	"fmt"
+	"regexp"
+	"time"
 )
 
 // VerifyRequest contains the verification params.
@@ -46,9 +48,70 @@ type VerifyRequest struct {
 	// in the contract (e.g. time-bound tokens)
 	CustomProviderHeaders []string
 
+	// FilterConsumers restricts verification to the pacts of the given consumers.
+	// Defaults to the comma separated list in the PACT_CONSUMER environment variable.
+	FilterConsumers []string
+
+	// FilterDescription is a regular expression, only interactions with a
+	// matching description will be verified.
+	// Defaults to the PACT_DESCRIPTION environment variable.
+	FilterDescription string
+
+	// FilterState is a regular expression, only interactions with a matching
+	// provider state will be verified.
+	// Defaults to the PACT_PROVIDER_STATE environment variable.
+	FilterState string
+
+	// Concurrency is the number of pacts to verify at the same time. Each pact
+	// is verified by its own verifier process. Unless ProviderInstances are
+	// given, the pacts are verified against the same ProviderBaseURL and
+	// ProviderStatesSetupURL, so the Provider must itself handle the states of
+	// several pacts being set up at the same time.
+	// Defaults to verifying all pacts sequentially in a single process, or to
+	// the number of ProviderInstances.
+	Concurrency int
+
+	// ProviderInstances isolate the provider states of pacts verified
+	// concurrently. Each pact is verified against an instance of the Provider
+	// that no other pact is being verified against at the same time, so
+	// Concurrency may not exceed the number of instances. Replaces the
+	// ProviderBaseURL and ProviderStatesSetupURL. Optional.
+	ProviderInstances []ProviderInstance
+
+	// Timeout is the maximum duration of the verification, after which the
+	// verifier process is stopped. Set from the context deadline when using
+	// PactClient.VerifyProviderContext. Optional.
+	Timeout time.Duration
+
+	// VerificationID identifies a running verification, so that it may be
+	// cancelled. Set by PactClient.VerifyProviderContext. Optional.
+	VerificationID string
+
+	// JUnitReportFile is the path to write a JUnit XML report of the
+	// verification results to. Optional.
+	JUnitReportFile string
+
+	// TAPReportFile is the path to write a TAP (Test Anything Protocol) report
+	// of the verification results to. Optional.
+	TAPReportFile string
+
 	// Arguments to the VerificationProvider
 	// Deprecated: This will be deleted after the native library replaces Ruby deps.
 	Args []string
+
+	// Environment variables to the VerificationProvider
+	// Deprecated: This will be deleted after the native library replaces Ruby deps.
+	Env []string
+}
+
+// ProviderInstance is an instance of the Provider, with its own provider
+// states, to verify pacts against concurrently.
+type ProviderInstance struct {
+	// BaseURL of the instance.
+	BaseURL string
+
+	// StatesSetupURL to post the provider state to on the instance. Optional.
+	StatesSetupURL string
 }
 
 // Validate checks that the minimum fields are provided.
@@ -56,6 +119,7 @@ type VerifyRequest struct {
 // and should not be used outside of this library.
 func (v *VerifyRequest) Validate() error {
 	v.Args = []string{}
+	v.Env = []string{}
 
 	if len(v.PactURLs) != 0 {
 		v.Args = append(v.Args, v.PactURLs...)
@@ -105,5 +169,20 @@ func (v *VerifyRequest) Validate() error {
 	if v.Verbose {
 		v.Args = append(v.Args, "--verbose", fmt.Sprintf("%v", v.Verbose))
 	}
+
+	// The verifier selects interactions from the environment
+	if v.FilterDescription != "" {
+		if _, err := regexp.Compile(v.FilterDescription); err != nil {
+			return fmt.Errorf("Invalid description filter: %s", err)
+		}
+		v.Env = append(v.Env, fmt.Sprintf("PACT_DESCRIPTION=%s", v.FilterDescription))
+	}
+
+	if v.FilterState != "" {
+		if _, err := regexp.Compile(v.FilterState); err != nil {
+			return fmt.Errorf("Invalid provider state filter: %s", err)
+		}
+		v.Env = append(v.Env, fmt.Sprintf("PACT_PROVIDER_STATE=%s", v.FilterState))
+	}
 	return nil
 }
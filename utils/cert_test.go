@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+)
+
+func Test_GenerateCertificate(t *testing.T) {
+	certPEM, keyPEM, err := GenerateCertificate([]string{"localhost", "127.0.0.1", "[::1]"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(certPEM) {
+		t.Fatalf("Expected certificate to be added to the pool")
+	}
+	for _, host := range []string{"localhost", "127.0.0.1", "::1"} {
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: pool}); err != nil {
+			t.Fatalf("Expected certificate to be valid for %s but got: %v", host, err)
+		}
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: "example.com", Roots: pool}); err == nil {
+		t.Fatalf("Expected certificate to be invalid for example.com")
+	}
+}
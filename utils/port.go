@@ -12,7 +12,13 @@ import (
 // GetFreePort Gets an available port by asking the kernal for a random port
 // ready and available for use.
 func GetFreePort() (int, error) {
-	addr, err := net.ResolveTCPAddr("tcp", "localhost:0")
+	return GetFreePortOnHost("localhost")
+}
+
+// GetFreePortOnHost gets an available port on the given host, e.g.
+// 'localhost', '127.0.0.1' or '[::1]', by asking the kernel for a random port.
+func GetFreePortOnHost(host string) (int, error) {
+	addr, err := net.ResolveTCPAddr("tcp", hostPort(host, 0))
 	if err != nil {
 		return 0, err
 	}
@@ -26,54 +32,81 @@ func GetFreePort() (int, error) {
 }
 
 // FindPortInRange Iterate through CSV or Range of ports to find open port
-// Valid inputs are "8081", "8081,8085", "8081-8085". Do not combine
-// list and range
+// Valid inputs are "8081", "8081,8085", "8081-8085" or a mix of lists and
+// ranges, e.g. "8081,8085-8090".
 func FindPortInRange(s string) (int, error) {
-	// Take care of csv and single value
-	if !strings.Contains(s, "-") {
-		ports := strings.Split(strings.TrimSpace(s), ",")
-		for _, p := range ports {
-			i, err := strconv.Atoi(p)
-			if err != nil {
-				return 0, err
-			}
-			err = checkPort(i)
-			if err != nil {
-				continue
-			}
-			return i, nil
-		}
-		return 0, errors.New("all passed ports are unusable")
-	}
-	// Now take care of ranges
-	ports := strings.Split(strings.TrimSpace(s), "-")
-	if len(ports) != 2 {
-		return 0, errors.New("invalid range passed")
-	}
-	lower, err := strconv.Atoi(ports[0])
-	if err != nil {
-		return 0, err
-	}
-	upper, err := strconv.Atoi(ports[1])
+	return FindPortInRangeOnHost(s, "localhost")
+}
+
+// FindPortInRangeOnHost is FindPortInRange, for a port open on the given host.
+func FindPortInRangeOnHost(s string, host string) (int, error) {
+	ports, err := ParsePorts(s)
 	if err != nil {
 		return 0, err
 	}
-	if upper < lower {
-		return 0, errors.New("invalid range passed")
+
+	for _, p := range ports {
+		if PortAvailable(host, p) {
+			return p, nil
+		}
 	}
-	for i := lower; i <= upper; i++ {
-		err = checkPort(i)
+	return 0, errors.New("all passed ports are unusable")
+}
+
+// The range of valid TCP ports.
+const (
+	minPort = 1
+	maxPort = 65535
+)
+
+// ParsePorts parses a comma separated list of ports and ranges of ports,
+// e.g. "8081", "8081,8085", "8081-8085" or "8081,8085-8090", in order. Ports
+// outside 1-65535 and reversed ranges are rejected.
+func ParsePorts(s string) ([]int, error) {
+	var ports []int
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.Split(strings.TrimSpace(part), "-")
+		if len(bounds) > 2 {
+			return nil, errors.New("invalid range passed")
+		}
+
+		lower, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
 		if err != nil {
-			continue
+			return nil, err
+		}
+		upper := lower
+		if len(bounds) == 2 {
+			upper, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err != nil {
+				return nil, err
+			}
+		}
+		if lower < minPort || upper > maxPort {
+			return nil, fmt.Errorf("invalid port passed, ports must be between %d and %d", minPort, maxPort)
+		}
+		if upper < lower {
+			return nil, errors.New("invalid range passed")
+		}
+
+		for i := lower; i <= upper; i++ {
+			ports = append(ports, i)
 		}
-		return i, nil
 	}
-	return 0, errors.New("all passed ports are unusable")
+	return ports, nil
+}
+
+// PortAvailable reports whether the port can be listened on, on the given
+// host.
+func PortAvailable(host string, port int) bool {
+	return checkPortOnHost(host, port) == nil
 }
 
 func checkPort(p int) error {
-	s := fmt.Sprintf("localhost:%d", p)
-	addr, err := net.ResolveTCPAddr("tcp", s)
+	return checkPortOnHost("localhost", p)
+}
+
+func checkPortOnHost(host string, p int) error {
+	addr, err := net.ResolveTCPAddr("tcp", hostPort(host, p))
 	if err != nil {
 		return err
 	}
@@ -85,3 +118,12 @@ func checkPort(p int) error {
 	defer l.Close()
 	return nil
 }
+
+// hostPort joins a host, with or without brackets around an IPv6 address,
+// and port.
+func hostPort(host string, port int) string {
+	if host == "" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(port))
+}
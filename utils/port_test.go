@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"fmt"
 	"net"
+	"reflect"
 	"testing"
 )
 
@@ -69,6 +71,24 @@ func Test_FindPortInRange(t *testing.T) {
 		{
 			description: "double range",
 			s:           "6668-6669,7000-7001",
+			port:        6668,
+			errorMsg:    "",
+		},
+		{
+			description: "mixed list and range",
+			s:           "6667, 6668-6669",
+			port:        6667,
+			errorMsg:    "",
+		},
+		{
+			description: "invalid range in list",
+			s:           "6667,6669-6668",
+			port:        0,
+			errorMsg:    "invalid range passed",
+		},
+		{
+			description: "invalid multiple range",
+			s:           "6667-6668-6669",
 			port:        0,
 			errorMsg:    "invalid range passed",
 		},
@@ -137,6 +157,65 @@ func Test_FindPortInRangeWithUsedPorts(t *testing.T) {
 	}
 }
 
+func Test_ParsePorts(t *testing.T) {
+	ports, err := ParsePorts("6667,6670-6672, 6668")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !reflect.DeepEqual(ports, []int{6667, 6670, 6671, 6672, 6668}) {
+		t.Fatalf("Expected ports in order but got: %v", ports)
+	}
+}
+
+func Test_ParsePortsInvalid(t *testing.T) {
+	for _, s := range []string{"0", "65536", "1-2000000000", "-1", "6672-6670", "6667-6668-6669", "abc"} {
+		if ports, err := ParsePorts(s); err == nil {
+			t.Fatalf("Expected error for %q but got: %v", s, ports)
+		}
+	}
+
+	ports, err := ParsePorts("65534-65535")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !reflect.DeepEqual(ports, []int{65534, 65535}) {
+		t.Fatalf("Expected the highest ports but got: %v", ports)
+	}
+}
+
+func Test_FindPortInRangeOnHost(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer l.Close()
+	used := l.Addr().(*net.TCPAddr).Port
+
+	if PortAvailable("127.0.0.1", used) {
+		t.Fatalf("Expected port %d to be unavailable", used)
+	}
+
+	free, _ := GetFreePortOnHost("127.0.0.1")
+	p, err := FindPortInRangeOnHost(fmt.Sprintf("%d,%d", used, free), "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if p != free {
+		t.Fatalf("Expected port %d got %d", free, p)
+	}
+}
+
+func Test_GetFreePortOnHost(t *testing.T) {
+	port, err := GetFreePortOnHost("[::1]")
+	if err != nil {
+		t.Skipf("IPv6 is not available: %v", err)
+	}
+
+	if port <= 0 {
+		t.Fatalf("Expected a port > 0 to be available, got %d", port)
+	}
+}
+
 func Test_checkPort(t *testing.T) {
 	// Most cases tested above just have this one to test
 	err := checkPort(-100)